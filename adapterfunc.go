@@ -0,0 +1,62 @@
+package kurin
+
+import (
+	"context"
+	"os"
+)
+
+type funcAdapter struct {
+	open      func(ctx context.Context) error
+	close     func(ctx context.Context) error
+	onFailure func(error)
+}
+
+// AdapterFunc adapts an arbitrary lifecycle into an adapter: open is expected to block until
+// close is called, and an error it returns is fatal to the application. NotifyStop and OnFailure
+// are no-ops.
+func AdapterFunc(open func() error, close func() error) Adapter {
+	return ContextAdapterFunc(
+		func(context.Context) error { return open() },
+		func(context.Context) error { return close() },
+		nil,
+	)
+}
+
+// ContextAdapterFunc is like AdapterFunc with context aware closures, the open context being
+// cancelled when the application shuts down. onFailure, if not nil, receives the failures
+// reported by the fallible systems.
+func ContextAdapterFunc(open func(ctx context.Context) error, close func(ctx context.Context) error, onFailure func(error)) Adapter {
+	return &funcAdapter{open: open, close: close, onFailure: onFailure}
+}
+
+func (adapter *funcAdapter) Open() {
+	adapter.OpenContext(context.Background())
+}
+
+func (adapter *funcAdapter) OpenContext(ctx context.Context) error {
+	if adapter.open == nil {
+		return nil
+	}
+
+	return adapter.open(ctx)
+}
+
+func (adapter *funcAdapter) Close() {
+	adapter.CloseContext(context.Background())
+}
+
+func (adapter *funcAdapter) CloseContext(ctx context.Context) error {
+	if adapter.close == nil {
+		return nil
+	}
+
+	return adapter.close(ctx)
+}
+
+func (adapter *funcAdapter) NotifyStop(chan os.Signal) {}
+
+func (adapter *funcAdapter) OnFailure(err error) {
+	if adapter.onFailure != nil {
+		adapter.onFailure(err)
+	}
+}
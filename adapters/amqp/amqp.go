@@ -1,10 +1,16 @@
 package amqp
 
 import (
+	"context"
+	"fmt"
 	"os"
+	"sync"
+	"syscall"
+	"time"
 
 	"github.com/assembla/cony"
 	"github.com/maxperrimond/kurin"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/streadway/amqp"
 )
 
@@ -12,41 +18,255 @@ type (
 	Adapter struct {
 		client   *cony.Client
 		consumer *cony.Consumer
-		handler  DeliveryHandler
-		onStop   chan os.Signal
+		handler  FallibleDeliveryHandler
+		stop     kurin.StopNotifier
 		logger   kurin.Logger
+		breaker  *kurin.CircuitBreaker
+
+		registerer   prometheus.Registerer
+		breakerState prometheus.Gauge
+		closing      chan struct{}
+		closeOnce    sync.Once
+		workers      sync.WaitGroup
+
+		retries     *cony.Publisher
+		deadLetter  *cony.Publisher
+		maxRetries  int
+		requeue     bool
+		concurrency int
+		name        string
 	}
 
+	// DeliveryHandler handles a delivery, acking or nacking it itself.
 	DeliveryHandler func(msg amqp.Delivery)
+
+	// FallibleDeliveryHandler handles a delivery and acks it, returning nil. When it returns an
+	// error, the adapter owns the delivery and the handler must neither ack nor nack it: it is
+	// republished by WithDeadLetter, or else nacked, and requeued only with WithRequeue.
+	FallibleDeliveryHandler func(msg amqp.Delivery) error
+
+	Option func(*Adapter)
 )
 
-func NewAMQPAdapter(client *cony.Client, consumer *cony.Consumer, handler DeliveryHandler, logger kurin.Logger) kurin.Adapter {
-	return &Adapter{
+// circuitOpenRequeueDelay is the least a delivery refused by the open circuit waits before being
+// requeued, so that it is not redelivered right away while probes are in flight.
+const circuitOpenRequeueDelay = time.Second
+
+func NewAMQPAdapter(client *cony.Client, consumer *cony.Consumer, handler DeliveryHandler, logger kurin.Logger, options ...Option) kurin.Adapter {
+	return NewFallibleAMQPAdapter(client, consumer, func(msg amqp.Delivery) error {
+		handler(msg)
+		return nil
+	}, logger, options...)
+}
+
+// NewFallibleAMQPAdapter is NewAMQPAdapter for a handler returning its failures, which are counted
+// by WithCircuitBreaker and retried by WithDeadLetter.
+func NewFallibleAMQPAdapter(client *cony.Client, consumer *cony.Consumer, handler FallibleDeliveryHandler, logger kurin.Logger, options ...Option) kurin.Adapter {
+	adapter := &Adapter{
 		client:   client,
 		consumer: consumer,
 		handler:  handler,
 		logger:   logger,
+
+		registerer: prometheus.DefaultRegisterer,
+		closing:    make(chan struct{}),
+	}
+
+	for _, option := range options {
+		option(adapter)
+	}
+	if adapter.breaker != nil {
+		adapter.breakerState = prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "app_amqp_circuit_breaker_state",
+			Help: "State of the circuit breaker wrapping the AMQP handler (0 closed, 1 open, 2 half-open).",
+		})
+		adapter.register(adapter.breakerState)
+		adapter.breaker.OnStateChange(func(from, to kurin.CircuitState) {
+			adapter.breakerState.Set(float64(to))
+		})
+	}
+	if adapter.concurrency <= 0 {
+		adapter.concurrency = 1
+	}
+	concurrency := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "app_amqp_concurrency",
+		Help: "Number of AMQP deliveries handled in parallel.",
+	})
+	adapter.register(concurrency)
+	concurrency.Set(float64(adapter.concurrency))
+
+	return adapter
+}
+
+// WithConcurrency sets the number of deliveries handled in parallel, e.g. kurin.DefaultConcurrency.
+// The deliveries are handled one at a time by default, in order: they are no longer once n > 1. The
+// prefetch count of the channel should allow as many unacknowledged deliveries.
+func WithConcurrency(n int) Option {
+	return func(adapter *Adapter) {
+		adapter.concurrency = n
+	}
+}
+
+// WithCircuitBreaker runs the handler through the given breaker, which counts the failures of a
+// FallibleDeliveryHandler, see NewFallibleAMQPAdapter. While the circuit is open, deliveries are
+// requeued without calling the handler once the circuit lets probes through, the workers holding
+// them meanwhile so that no more deliveries are consumed. The state is exposed by the
+// app_amqp_circuit_breaker_state gauge (0 closed, 1 open, 2 half-open).
+func WithCircuitBreaker(breaker *kurin.CircuitBreaker) Option {
+	return func(adapter *Adapter) {
+		adapter.breaker = breaker
+	}
+}
+
+// WithRequeue requeues the deliveries whose handler failed, instead of nacking them for the broker to
+// discard them or route them to the dead letter exchange of the queue. It has no effect along
+// WithDeadLetter, which republishes them.
+func WithRequeue(requeue bool) Option {
+	return func(adapter *Adapter) {
+		adapter.requeue = requeue
+	}
+}
+
+// WithName names the adapter, to tell it apart from the other adapters of the application in its
+// logs, events and metrics, see kurin.Named.
+func WithName(name string) Option {
+	return func(adapter *Adapter) {
+		adapter.name = name
+	}
+}
+
+// WithRegisterer sets the registerer of the adapter metrics, prometheus.DefaultRegisterer by default.
+func WithRegisterer(registerer prometheus.Registerer) Option {
+	return func(adapter *Adapter) {
+		adapter.registerer = registerer
+	}
+}
+
+// register registers the collector on the registerer of the adapter. A collector already
+// registered, by another adapter on the same registerer, is only logged.
+func (adapter *Adapter) register(collector prometheus.Collector) {
+	if err := adapter.registerer.Register(collector); err != nil {
+		adapter.logger.Warn(fmt.Sprintf("Unable to register the amqp metrics, give every adapter its own registerer: %s", err))
 	}
 }
 
 func (adapter *Adapter) Open() {
-	adapter.logger.Info("Consuming amqp...")
+	if err := adapter.OpenContext(context.Background()); err != nil {
+		adapter.logger.Fatal(err)
+	}
+}
+
+// OpenContext consumes the deliveries until the adapter or the client is closed, the client fails
+// or ctx is cancelled, waiting for the deliveries being handled before returning. A failure of the
+// client is returned wrapping kurin.ErrDependency.
+func (adapter *Adapter) OpenContext(ctx context.Context) error {
+	adapter.logger.Info(fmt.Sprintf("Consuming amqp with %d workers...", adapter.concurrency))
+
+	deliveries := make(chan amqp.Delivery)
+	adapter.workers.Add(adapter.concurrency)
+	for i := 0; i < adapter.concurrency; i++ {
+		go func() {
+			defer adapter.workers.Done()
+			for msg := range deliveries {
+				adapter.handle(msg)
+			}
+		}()
+	}
+	defer func() {
+		close(deliveries)
+		adapter.workers.Wait()
+	}()
+
 	for adapter.client.Loop() {
 		select {
-		case msg := <-adapter.consumer.Deliveries():
-			adapter.handler(msg)
+		case msg, ok := <-adapter.consumer.Deliveries():
+			if !ok {
+				return nil
+			}
+			select {
+			case deliveries <- msg:
+			case <-adapter.closing:
+				// Left unacknowledged, msg is redelivered once the channel is closed.
+				return nil
+			}
 		case err := <-adapter.client.Errors():
-			adapter.logger.Fatal(err)
+			return fmt.Errorf("%w: amqp: %w", kurin.ErrDependency, err)
+		case <-adapter.closing:
+			return nil
+		case <-ctx.Done():
+			return nil
+		}
+	}
+
+	return nil
+}
+
+func (adapter *Adapter) handle(msg amqp.Delivery) {
+	var err error
+	if adapter.breaker == nil {
+		err = adapter.handler(msg)
+	} else {
+		err = adapter.breaker.Execute(func() error {
+			return adapter.handler(msg)
+		})
+	}
+
+	switch {
+	case err == kurin.ErrCircuitOpen:
+		delay := adapter.breaker.RetryAfter()
+		if delay < circuitOpenRequeueDelay {
+			delay = circuitOpenRequeueDelay
+		}
+		select {
+		case <-time.After(delay):
+		case <-adapter.closing:
+		}
+		if err := msg.Nack(false, true); err != nil {
+			adapter.logger.Error(err)
+		}
+	case err != nil:
+		adapter.logger.Error(err)
+		if adapter.deadLetter != nil {
+			adapter.retry(msg)
+		} else if err := msg.Nack(false, adapter.requeue); err != nil {
+			adapter.logger.Error(err)
 		}
 	}
 }
 
+// Close stops consuming and waits for the deliveries being handled, so that they are acknowledged
+// on the channel they came from, before closing the consumer and the client.
 func (adapter *Adapter) Close() {
+	adapter.closeOnce.Do(func() {
+		close(adapter.closing)
+	})
+	adapter.stop.Stop(syscall.SIGTERM)
+	adapter.workers.Wait()
+	adapter.consumer.Cancel()
 	adapter.client.Close()
 }
 
+// Name is the name given by WithName, if any.
+func (adapter *Adapter) Name() string {
+	return adapter.name
+}
+
+// NotifyStop subscribes c to the adapter stop, notified with SIGTERM when it starts closing.
+func (adapter *Adapter) NotifyStop(c chan os.Signal) {
+	adapter.stop.NotifyStop(c)
+}
+
+// NotifyFail forwards the circuit breaker openings, if any, to the application.
+func (adapter *Adapter) NotifyFail(c chan error) {
+	if adapter.breaker != nil {
+		adapter.breaker.NotifyFail(c)
+	}
+}
+
+// OnFailure logs the failures reported by the systems of the application, which are recoverable
+// and keep the adapter consuming. Its own fatal failures are returned by OpenContext instead.
 func (adapter *Adapter) OnFailure(err error) {
-	if err != nil {
-		adapter.logger.Fatal(err)
+	if err != nil && err != kurin.ErrCircuitOpen {
+		adapter.logger.Warn(fmt.Sprintf("System failure reported, still consuming: %s", err))
 	}
 }
@@ -0,0 +1,67 @@
+package amqp
+
+import (
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/maxperrimond/kurin"
+	"github.com/streadway/amqp"
+)
+
+type (
+	nack struct {
+		tag     uint64
+		requeue bool
+	}
+
+	recordingAcknowledger struct {
+		acks  []uint64
+		nacks []nack
+	}
+)
+
+func (acknowledger *recordingAcknowledger) Ack(tag uint64, multiple bool) error {
+	acknowledger.acks = append(acknowledger.acks, tag)
+	return nil
+}
+
+func (acknowledger *recordingAcknowledger) Nack(tag uint64, multiple bool, requeue bool) error {
+	acknowledger.nacks = append(acknowledger.nacks, nack{tag, requeue})
+	return nil
+}
+
+func (acknowledger *recordingAcknowledger) Reject(tag uint64, requeue bool) error {
+	return acknowledger.Nack(tag, false, requeue)
+}
+
+func TestFailedDeliveryNacked(t *testing.T) {
+	for _, requeue := range []bool{false, true} {
+		adapter := &Adapter{
+			handler: func(msg amqp.Delivery) error { return errors.New("failed") },
+			logger:  kurin.NewStdLogger(io.Discard, kurin.LevelError),
+		}
+		WithRequeue(requeue)(adapter)
+		acknowledger := &recordingAcknowledger{}
+
+		adapter.handle(amqp.Delivery{Acknowledger: acknowledger, DeliveryTag: 1})
+
+		if len(acknowledger.acks) != 0 || len(acknowledger.nacks) != 1 || acknowledger.nacks[0] != (nack{1, requeue}) {
+			t.Errorf("requeue %v: acks %v and nacks %v, want a single nack", requeue, acknowledger.acks, acknowledger.nacks)
+		}
+	}
+}
+
+func TestHandledDeliveryLeftToHandler(t *testing.T) {
+	adapter := &Adapter{
+		handler: func(msg amqp.Delivery) error { return msg.Ack(false) },
+		logger:  kurin.NewStdLogger(io.Discard, kurin.LevelError),
+	}
+	acknowledger := &recordingAcknowledger{}
+
+	adapter.handle(amqp.Delivery{Acknowledger: acknowledger, DeliveryTag: 1})
+
+	if len(acknowledger.acks) != 1 || len(acknowledger.nacks) != 0 {
+		t.Errorf("acks %v and nacks %v, want the single ack of the handler", acknowledger.acks, acknowledger.nacks)
+	}
+}
@@ -0,0 +1,89 @@
+package amqp
+
+import (
+	"fmt"
+
+	"github.com/assembla/cony"
+	"github.com/streadway/amqp"
+)
+
+const retryCountHeader = "x-retry-count"
+
+// WithDeadLetter stops failing deliveries from being redelivered forever. A delivery whose handler
+// fails is republished to queue, the queue consumed, with its x-retry-count header incremented,
+// until it failed maxRetries times. It is then published to destination instead, the routing key
+// of the dead letter queue. Both are published through the default exchange, which routes them to
+// that single queue rather than to every queue bound to the original exchange.
+//
+// The failed delivery, owned by the adapter, is acked once republished, or requeued when the
+// publishing fails. Only the failures of a FallibleDeliveryHandler are retried, see
+// NewFallibleAMQPAdapter.
+func WithDeadLetter(queue, destination string, maxRetries int) Option {
+	return func(adapter *Adapter) {
+		adapter.maxRetries = maxRetries
+		adapter.retries = cony.NewPublisher("", queue)
+		adapter.deadLetter = cony.NewPublisher("", destination)
+		adapter.client.Publish(adapter.retries)
+		adapter.client.Publish(adapter.deadLetter)
+	}
+}
+
+func (adapter *Adapter) retry(msg amqp.Delivery) {
+	failures := retryCount(msg.Headers) + 1
+	publisher := adapter.deadLetter
+	if failures < adapter.maxRetries {
+		publisher = adapter.retries
+	}
+
+	headers := amqp.Table{}
+	for key, value := range msg.Headers {
+		headers[key] = value
+	}
+	headers[retryCountHeader] = int32(failures)
+
+	err := publisher.Publish(amqp.Publishing{
+		Headers:         headers,
+		ContentType:     msg.ContentType,
+		ContentEncoding: msg.ContentEncoding,
+		DeliveryMode:    msg.DeliveryMode,
+		Priority:        msg.Priority,
+		CorrelationId:   msg.CorrelationId,
+		ReplyTo:         msg.ReplyTo,
+		Expiration:      msg.Expiration,
+		MessageId:       msg.MessageId,
+		Timestamp:       msg.Timestamp,
+		Type:            msg.Type,
+		UserId:          msg.UserId,
+		AppId:           msg.AppId,
+		Body:            msg.Body,
+	})
+	if err != nil {
+		adapter.logger.Error(fmt.Errorf("republishing delivery: %v", err))
+		if err := msg.Nack(false, true); err != nil {
+			adapter.logger.Error(err)
+		}
+		return
+	}
+
+	if publisher == adapter.deadLetter {
+		adapter.logger.Warn(fmt.Sprintf("Delivery failed %d times, sent to the dead letter exchange", failures))
+	}
+	if err := msg.Ack(false); err != nil {
+		adapter.logger.Error(err)
+	}
+}
+
+func retryCount(headers amqp.Table) int {
+	switch count := headers[retryCountHeader].(type) {
+	case int:
+		return count
+	case int16:
+		return int(count)
+	case int32:
+		return int(count)
+	case int64:
+		return int(count)
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,235 @@
+package cache
+
+import (
+	"container/list"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/maxperrimond/kurin"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type (
+	// Adapter is an in-memory cache whose entries expire after their TTL, the least recently used
+	// ones being evicted beyond the max size if any. The expired entries are removed by a janitor
+	// running while the adapter is open.
+	Adapter struct {
+		name            string
+		logger          kurin.Logger
+		clock           kurin.Clock
+		ttl             time.Duration
+		maxSize         int
+		janitorInterval time.Duration
+		registerer      prometheus.Registerer
+
+		mu      sync.Mutex
+		entries map[string]*list.Element
+		lru     *list.List
+
+		size      prometheus.Gauge
+		hits      prometheus.Counter
+		misses    prometheus.Counter
+		evictions prometheus.Counter
+
+		stop     chan struct{}
+		stopOnce sync.Once
+	}
+
+	entry struct {
+		key     string
+		value   interface{}
+		expires time.Time
+	}
+
+	Option func(*Adapter)
+)
+
+// NewCacheAdapter creates a cache whose metrics are labeled with its name: app_cache_entries,
+// app_cache_hits_total, app_cache_misses_total and app_cache_evictions_total.
+func NewCacheAdapter(name string, logger kurin.Logger, options ...Option) *Adapter {
+	adapter := &Adapter{
+		name:            name,
+		logger:          logger,
+		clock:           kurin.SystemClock,
+		ttl:             5 * time.Minute,
+		janitorInterval: time.Minute,
+		registerer:      prometheus.DefaultRegisterer,
+		entries:         make(map[string]*list.Element),
+		lru:             list.New(),
+		stop:            make(chan struct{}),
+	}
+
+	for _, option := range options {
+		option(adapter)
+	}
+
+	labels := prometheus.Labels{"cache": name}
+	adapter.size = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name:        "app_cache_entries",
+		Help:        "Number of entries in the cache.",
+		ConstLabels: labels,
+	})
+	adapter.hits = prometheus.NewCounter(prometheus.CounterOpts{
+		Name:        "app_cache_hits_total",
+		Help:        "A counter for lookups finding an entry in the cache.",
+		ConstLabels: labels,
+	})
+	adapter.misses = prometheus.NewCounter(prometheus.CounterOpts{
+		Name:        "app_cache_misses_total",
+		Help:        "A counter for lookups finding no entry, or an expired one, in the cache.",
+		ConstLabels: labels,
+	})
+	adapter.evictions = prometheus.NewCounter(prometheus.CounterOpts{
+		Name:        "app_cache_evictions_total",
+		Help:        "A counter for entries evicted from the cache, expired or beyond its max size.",
+		ConstLabels: labels,
+	})
+	adapter.registerer.MustRegister(adapter.size, adapter.hits, adapter.misses, adapter.evictions)
+
+	return adapter
+}
+
+// WithTTL sets the TTL of the entries set with Set, 5 minutes by default.
+func WithTTL(d time.Duration) Option {
+	return func(adapter *Adapter) {
+		adapter.ttl = d
+	}
+}
+
+// WithMaxSize bounds the number of entries, the least recently used one being evicted to make
+// room for a new one. The cache is only bounded by the TTL by default.
+func WithMaxSize(n int) Option {
+	return func(adapter *Adapter) {
+		adapter.maxSize = n
+	}
+}
+
+// WithJanitorInterval sets how often the expired entries are removed, every minute by default.
+func WithJanitorInterval(d time.Duration) Option {
+	return func(adapter *Adapter) {
+		adapter.janitorInterval = d
+	}
+}
+
+func WithRegisterer(registerer prometheus.Registerer) Option {
+	return func(adapter *Adapter) {
+		adapter.registerer = registerer
+	}
+}
+
+// WithClock sets the clock expiring the entries, the system clock by default.
+func WithClock(clock kurin.Clock) Option {
+	return func(adapter *Adapter) {
+		adapter.clock = clock
+	}
+}
+
+// Get returns the value of the key unless it is missing or expired.
+func (adapter *Adapter) Get(key string) (interface{}, bool) {
+	adapter.mu.Lock()
+	defer adapter.mu.Unlock()
+
+	element, ok := adapter.entries[key]
+	if !ok {
+		adapter.misses.Inc()
+		return nil, false
+	}
+	e := element.Value.(*entry)
+	if !adapter.clock.Now().Before(e.expires) {
+		adapter.remove(element)
+		adapter.evictions.Inc()
+		adapter.misses.Inc()
+		return nil, false
+	}
+
+	adapter.lru.MoveToFront(element)
+	adapter.hits.Inc()
+
+	return e.value, true
+}
+
+// Set sets the value of the key with the TTL of the cache.
+func (adapter *Adapter) Set(key string, value interface{}) {
+	adapter.SetWithTTL(key, value, adapter.ttl)
+}
+
+// SetWithTTL sets the value of the key, expiring after ttl.
+func (adapter *Adapter) SetWithTTL(key string, value interface{}, ttl time.Duration) {
+	adapter.mu.Lock()
+	defer adapter.mu.Unlock()
+
+	expires := adapter.clock.Now().Add(ttl)
+	if element, ok := adapter.entries[key]; ok {
+		e := element.Value.(*entry)
+		e.value, e.expires = value, expires
+		adapter.lru.MoveToFront(element)
+		return
+	}
+
+	adapter.entries[key] = adapter.lru.PushFront(&entry{key: key, value: value, expires: expires})
+	if adapter.maxSize > 0 && adapter.lru.Len() > adapter.maxSize {
+		adapter.remove(adapter.lru.Back())
+		adapter.evictions.Inc()
+	}
+	adapter.size.Set(float64(adapter.lru.Len()))
+}
+
+func (adapter *Adapter) Delete(key string) {
+	adapter.mu.Lock()
+	defer adapter.mu.Unlock()
+
+	if element, ok := adapter.entries[key]; ok {
+		adapter.remove(element)
+	}
+}
+
+// Len returns the number of entries, the expired ones not yet removed included.
+func (adapter *Adapter) Len() int {
+	adapter.mu.Lock()
+	defer adapter.mu.Unlock()
+
+	return adapter.lru.Len()
+}
+
+func (adapter *Adapter) remove(element *list.Element) {
+	adapter.lru.Remove(element)
+	delete(adapter.entries, element.Value.(*entry).key)
+	adapter.size.Set(float64(adapter.lru.Len()))
+}
+
+// Open runs the janitor until the adapter is closed.
+func (adapter *Adapter) Open() {
+	adapter.logger.Info(fmt.Sprintf("Removing the expired entries of the %s cache every %s...", adapter.name, adapter.janitorInterval))
+	for {
+		select {
+		case <-adapter.clock.After(adapter.janitorInterval):
+			adapter.removeExpired()
+		case <-adapter.stop:
+			return
+		}
+	}
+}
+
+func (adapter *Adapter) removeExpired() {
+	adapter.mu.Lock()
+	defer adapter.mu.Unlock()
+
+	now := adapter.clock.Now()
+	for _, element := range adapter.entries {
+		if !now.Before(element.Value.(*entry).expires) {
+			adapter.remove(element)
+			adapter.evictions.Inc()
+		}
+	}
+}
+
+// Close stops the janitor, the cache can still be used.
+func (adapter *Adapter) Close() {
+	adapter.stopOnce.Do(func() {
+		close(adapter.stop)
+	})
+}
+
+func (adapter *Adapter) OnFailure(err error) {
+}
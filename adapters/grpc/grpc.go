@@ -0,0 +1,251 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/maxperrimond/kurin"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"google.golang.org/grpc"
+)
+
+type (
+	// Adapter runs a gRPC server along its REST gateway (e.g. generated by grpc-gateway), on
+	// separate ports or multiplexed on a single one.
+	Adapter struct {
+		server      *grpc.Server
+		srv         *http.Server
+		host        string
+		grpcPort    int
+		gatewayPort int
+		logger      kurin.Logger
+		name        string
+
+		// The requests served over the multiplexed port, on connections hijacked by h2c, which
+		// neither GracefulStop nor the server shutdown drain.
+		calls   sync.WaitGroup
+		conns   map[net.Conn]struct{}
+		mu      sync.Mutex
+		closing bool
+	}
+
+	// trackingListener keeps track of the connections of the multiplexed port, the hijacked ones
+	// being closed by CloseContext.
+	trackingListener struct {
+		net.Listener
+		adapter *Adapter
+	}
+
+	trackedConn struct {
+		net.Conn
+		adapter *Adapter
+	}
+
+	Option func(*Adapter)
+)
+
+// NewGRPCAdapter serves the gRPC server on grpcPort and the gateway on gatewayPort. When both ports
+// are the same, they share it over cleartext HTTP/2 (h2c): HTTP/2 requests with an application/grpc
+// content type go to the gRPC server, the others to the gateway.
+func NewGRPCAdapter(server *grpc.Server, gateway http.Handler, host string, grpcPort int, gatewayPort int, logger kurin.Logger, options ...Option) kurin.Adapter {
+	adapter := &Adapter{
+		server:      server,
+		host:        host,
+		grpcPort:    grpcPort,
+		gatewayPort: gatewayPort,
+		logger:      logger,
+		conns:       make(map[net.Conn]struct{}),
+	}
+
+	for _, option := range options {
+		option(adapter)
+	}
+
+	adapter.srv = &http.Server{
+		Addr:    fmt.Sprintf("%s:%d", host, gatewayPort),
+		Handler: gateway,
+	}
+	if adapter.multiplexed() {
+		h2s := &http2.Server{}
+		// The shutdown of the server sends a GOAWAY on the h2c connections, tracked by h2s.
+		if err := http2.ConfigureServer(adapter.srv, h2s); err != nil {
+			adapter.logger.Warn(fmt.Sprintf("Unable to drain the h2c connections on shutdown: %s", err))
+		}
+		adapter.srv.Handler = h2c.NewHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			adapter.serveMultiplexed(w, r, gateway)
+		}), h2s)
+	}
+
+	return adapter
+}
+
+// WithName names the adapter, to tell it apart from the other adapters of the application in its
+// logs, events and metrics, see kurin.Named.
+func WithName(name string) Option {
+	return func(adapter *Adapter) {
+		adapter.name = name
+	}
+}
+
+func (adapter *Adapter) multiplexed() bool {
+	return adapter.grpcPort == adapter.gatewayPort
+}
+
+// serveMultiplexed serves a gRPC call or a gateway request over the multiplexed port, keeping track
+// of it until it returns. The requests are refused once closing.
+func (adapter *Adapter) serveMultiplexed(w http.ResponseWriter, r *http.Request, gateway http.Handler) {
+	adapter.mu.Lock()
+	if adapter.closing {
+		adapter.mu.Unlock()
+		http.Error(w, "shutting down", http.StatusServiceUnavailable)
+		return
+	}
+	adapter.calls.Add(1)
+	adapter.mu.Unlock()
+	defer adapter.calls.Done()
+
+	if r.ProtoMajor == 2 && strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+		adapter.server.ServeHTTP(w, r)
+		return
+	}
+	gateway.ServeHTTP(w, r)
+}
+
+func (adapter *Adapter) Open() {
+	if err := adapter.OpenContext(context.Background()); err != nil {
+		adapter.logger.Fatal(err)
+	}
+}
+
+// OpenContext serves until the servers stop or ctx is cancelled, leaving the shutdown to Close
+// in that case.
+func (adapter *Adapter) OpenContext(ctx context.Context) error {
+	gatewayListener, err := net.Listen("tcp", adapter.srv.Addr)
+	if err != nil {
+		return fmt.Errorf("%w: %w", kurin.ErrBind, err)
+	}
+
+	served := make(chan error, 2)
+	if adapter.multiplexed() {
+		gatewayListener = &trackingListener{Listener: gatewayListener, adapter: adapter}
+	} else {
+		grpcListener, err := net.Listen("tcp", fmt.Sprintf("%s:%d", adapter.host, adapter.grpcPort))
+		if err != nil {
+			gatewayListener.Close()
+			return fmt.Errorf("%w: %w", kurin.ErrBind, err)
+		}
+
+		adapter.logger.Info(fmt.Sprintf("Serving gRPC on %s", grpcListener.Addr()))
+		go func() {
+			served <- adapter.server.Serve(grpcListener)
+		}()
+	}
+
+	adapter.logger.Info(fmt.Sprintf("Serving the gateway on http://%s", gatewayListener.Addr()))
+	go func() {
+		if err := adapter.srv.Serve(gatewayListener); err != http.ErrServerClosed {
+			served <- err
+			return
+		}
+		served <- nil
+	}()
+
+	select {
+	case err := <-served:
+		return err
+	case <-ctx.Done():
+		return nil
+	}
+}
+
+func (adapter *Adapter) Close() {
+	if err := adapter.CloseContext(context.Background()); err != nil {
+		adapter.logger.Error(err)
+	}
+}
+
+// CloseContext shuts the gateway down and stops the gRPC server gracefully, until ctx is done
+// when the remaining gRPC calls are cancelled. When multiplexed, the h2c connections are sent a
+// GOAWAY and the requests in flight, gRPC calls included, are waited for before stopping the
+// server, which cannot stop gracefully the calls it did not accept itself, and closing the
+// connections.
+func (adapter *Adapter) CloseContext(ctx context.Context) error {
+	err := adapter.srv.Shutdown(ctx)
+
+	if adapter.multiplexed() {
+		adapter.mu.Lock()
+		adapter.closing = true
+		adapter.mu.Unlock()
+
+		served := make(chan struct{})
+		go func() {
+			adapter.calls.Wait()
+			close(served)
+		}()
+		select {
+		case <-served:
+		case <-ctx.Done():
+		}
+		adapter.server.Stop()
+
+		adapter.mu.Lock()
+		conns := make([]net.Conn, 0, len(adapter.conns))
+		for conn := range adapter.conns {
+			conns = append(conns, conn)
+		}
+		adapter.mu.Unlock()
+		for _, conn := range conns {
+			conn.Close()
+		}
+
+		return err
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		adapter.server.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		adapter.server.Stop()
+	}
+
+	return err
+}
+
+func (adapter *Adapter) OnFailure(err error) {
+}
+
+// Name is the name given by WithName, if any.
+func (adapter *Adapter) Name() string {
+	return adapter.name
+}
+
+func (listener *trackingListener) Accept() (net.Conn, error) {
+	conn, err := listener.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+
+	listener.adapter.mu.Lock()
+	defer listener.adapter.mu.Unlock()
+	tracked := &trackedConn{Conn: conn, adapter: listener.adapter}
+	listener.adapter.conns[tracked] = struct{}{}
+
+	return tracked, nil
+}
+
+func (conn *trackedConn) Close() error {
+	conn.adapter.mu.Lock()
+	delete(conn.adapter.conns, conn)
+	conn.adapter.mu.Unlock()
+
+	return conn.Conn.Close()
+}
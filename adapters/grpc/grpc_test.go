@@ -0,0 +1,69 @@
+package grpc
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/maxperrimond/kurin"
+	"golang.org/x/net/http2"
+	"google.golang.org/grpc"
+)
+
+func freePort(t *testing.T) int {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	return listener.Addr().(*net.TCPAddr).Port
+}
+
+func TestMultiplexedCloseDrainsConnections(t *testing.T) {
+	port := freePort(t)
+	gateway := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+	adapter := NewGRPCAdapter(grpc.NewServer(), gateway, "127.0.0.1", port, port, kurin.NewStdLogger(io.Discard, kurin.LevelError)).(*Adapter)
+	served := make(chan error, 1)
+	go func() { served <- adapter.OpenContext(context.Background()) }()
+
+	client := &http.Client{Transport: &http2.Transport{
+		AllowHTTP: true,
+		DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, network, addr)
+		},
+	}}
+	url := "http://" + adapter.srv.Addr + "/"
+	var resp *http.Response
+	var err error
+	for start := time.Now(); time.Since(start) < time.Second; time.Sleep(10 * time.Millisecond) {
+		if resp, err = client.Get(url); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := adapter.CloseContext(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-served; err != nil {
+		t.Fatal(err)
+	}
+	if resp, err := client.Get(url); err == nil {
+		resp.Body.Close()
+		t.Errorf("got %s on the h2c connection once closed, want it gone", resp.Status)
+	}
+}
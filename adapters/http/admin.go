@@ -0,0 +1,64 @@
+package http
+
+import (
+	"encoding/json"
+	"expvar"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+type (
+	routeResponse struct {
+		Name    string   `json:"name,omitempty"`
+		Path    string   `json:"path"`
+		Methods []string `json:"methods,omitempty"`
+	}
+)
+
+func (adapter *Adapter) mountAdmin(mux *http.ServeMux) {
+	prefix := strings.TrimSuffix(adapter.config.AdminPath, "/")
+
+	admin := http.NewServeMux()
+	admin.HandleFunc(prefix+"/routes", adapter.routesHandler)
+	if adapter.debugVars {
+		admin.Handle(prefix+"/debug/vars", expvar.Handler())
+	}
+	if adapter.failures != nil {
+		admin.HandleFunc(prefix+"/errors", adapter.failuresHandler)
+	}
+
+	var handler http.Handler = admin
+	for i := len(adapter.adminMiddlewares) - 1; i >= 0; i-- {
+		handler = adapter.adminMiddlewares[i](handler)
+	}
+	mux.Handle(prefix+"/", handler)
+}
+
+func (adapter *Adapter) routesHandler(w http.ResponseWriter, r *http.Request) {
+	routes := make([]*routeResponse, 0)
+	err := adapter.router.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		path, err := route.GetPathTemplate()
+		if err != nil {
+			// Routes without path, such as host or header only ones, wrap sub-routers walked anyway.
+			return nil
+		}
+		methods, _ := route.GetMethods()
+
+		routes = append(routes, &routeResponse{
+			Name:    route.GetName(),
+			Path:    path,
+			Methods: methods,
+		})
+		return nil
+	})
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(routes)
+}
@@ -0,0 +1,44 @@
+package http
+
+import (
+	"github.com/maxperrimond/kurin"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type appCollector struct {
+	app       *kurin.App
+	total     *prometheus.Desc
+	unhealthy *prometheus.Desc
+	info      *prometheus.Desc
+}
+
+// NewAppCollector exposes the lifecycle of the application adapters: app_adapters_total,
+// app_adapters_unhealthy and app_adapter_info, labeled by adapter name and state. It reads the
+// states on every scrape, e.g. registered on the registry of the adapter given to the app.
+func NewAppCollector(app *kurin.App) prometheus.Collector {
+	return &appCollector{
+		app:       app,
+		total:     prometheus.NewDesc("app_adapters_total", "Number of adapters of the application.", nil, nil),
+		unhealthy: prometheus.NewDesc("app_adapters_unhealthy", "Number of unhealthy adapters of the application.", nil, nil),
+		info:      prometheus.NewDesc("app_adapter_info", "State of an adapter of the application, always 1.", []string{"adapter", "state"}, nil),
+	}
+}
+
+func (collector *appCollector) Describe(descs chan<- *prometheus.Desc) {
+	descs <- collector.total
+	descs <- collector.unhealthy
+	descs <- collector.info
+}
+
+func (collector *appCollector) Collect(metrics chan<- prometheus.Metric) {
+	statuses := collector.app.AdapterStatuses()
+	unhealthy := 0
+	for _, status := range statuses {
+		if status.State == kurin.AdapterUnhealthy {
+			unhealthy++
+		}
+		metrics <- prometheus.MustNewConstMetric(collector.info, prometheus.GaugeValue, 1, status.Name, status.State.String())
+	}
+	metrics <- prometheus.MustNewConstMetric(collector.total, prometheus.GaugeValue, float64(len(statuses)))
+	metrics <- prometheus.MustNewConstMetric(collector.unhealthy, prometheus.GaugeValue, float64(unhealthy))
+}
@@ -0,0 +1,129 @@
+package http
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+const defaultCaptureMaxBytes = 4096
+
+type (
+	// BodyCaptureConfig configures WithBodyCapture.
+	BodyCaptureConfig struct {
+		// Routes are the path templates of the routes whose bodies are captured, e.g. "/users/{id}".
+		Routes []string
+		// MaxBytes caps the captured size of each body, 4KB by default.
+		MaxBytes int
+		// Redact, when set, is applied to the captured bodies before they are logged, e.g. to
+		// strip passwords or tokens. A body truncated to MaxBytes is then left out of the logs, as
+		// a secret cut in the middle could escape the redaction.
+		Redact func(body []byte) []byte
+	}
+
+	capture struct {
+		body      []byte
+		max       int
+		truncated bool
+	}
+
+	captureReader struct {
+		io.ReadCloser
+		capture *capture
+	}
+
+	captureResponseWriter struct {
+		http.ResponseWriter
+		capture *capture
+		status  int
+	}
+)
+
+func (adapter *Adapter) bodyCaptureMiddleware(config BodyCaptureConfig) Middleware {
+	if config.MaxBytes <= 0 {
+		config.MaxBytes = defaultCaptureMaxBytes
+	}
+	routes := make(map[string]bool, len(config.Routes))
+	for _, route := range config.Routes {
+		routes[route] = true
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var match mux.RouteMatch
+			if !adapter.router.Match(r, &match) || match.Route == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if template, _ := match.Route.GetPathTemplate(); !routes[template] {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			request := &capture{max: config.MaxBytes}
+			response := &capture{max: config.MaxBytes}
+			if r.Body != nil {
+				r.Body = &captureReader{ReadCloser: r.Body, capture: request}
+			}
+			cw := &captureResponseWriter{ResponseWriter: w, capture: response, status: http.StatusOK}
+			next.ServeHTTP(cw, r)
+
+			adapter.logger.Debug(fmt.Sprintf("Request body of %s %s: %s", r.Method, r.URL.Path, request.String(config.Redact)))
+			adapter.logger.Debug(fmt.Sprintf("Response body of %s %s (%d): %s", r.Method, r.URL.Path, cw.status, response.String(config.Redact)))
+		})
+	}
+}
+
+func (capture *capture) write(b []byte) {
+	if remaining := capture.max - len(capture.body); len(b) > remaining {
+		b = b[:remaining]
+		capture.truncated = true
+	}
+	capture.body = append(capture.body, b...)
+}
+
+func (capture *capture) String(redact func([]byte) []byte) string {
+	body := capture.body
+	if redact != nil {
+		if capture.truncated {
+			return fmt.Sprintf("(truncated body of %d bytes or more, not logged as it cannot be redacted)", len(body))
+		}
+		body = redact(body)
+	}
+	if capture.truncated {
+		return fmt.Sprintf("%q (truncated)", body)
+	}
+
+	return fmt.Sprintf("%q", body)
+}
+
+func (reader *captureReader) Read(p []byte) (int, error) {
+	n, err := reader.ReadCloser.Read(p)
+	reader.capture.write(p[:n])
+
+	return n, err
+}
+
+func (cw *captureResponseWriter) WriteHeader(code int) {
+	cw.status = code
+	cw.ResponseWriter.WriteHeader(code)
+}
+
+func (cw *captureResponseWriter) Write(b []byte) (int, error) {
+	n, err := cw.ResponseWriter.Write(b)
+	cw.capture.write(b[:n])
+
+	return n, err
+}
+
+func (cw *captureResponseWriter) Flush() {
+	if f, ok := cw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (cw *captureResponseWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
@@ -0,0 +1,22 @@
+package http
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestTruncatedCaptureNotRedacted(t *testing.T) {
+	redact := func(body []byte) []byte {
+		return bytes.ReplaceAll(body, []byte(`"secret"`), []byte(`"***"`))
+	}
+	capture := &capture{max: 24}
+	capture.write([]byte(`{"password":"secret","name":"joe"}`))
+
+	if got := capture.String(redact); strings.Contains(got, "secr") {
+		t.Errorf("got %s, want the truncated body left out", got)
+	}
+	if got := capture.String(nil); got != `"{\"password\":\"secret\",\"na" (truncated)` {
+		t.Errorf("got %s, want the truncated body without redactor", got)
+	}
+}
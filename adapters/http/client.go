@@ -0,0 +1,192 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/maxperrimond/kurin"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type (
+	// ClientOption configures the client returned by NewHTTPClient.
+	ClientOption func(*clientTransport)
+
+	// clientTransport propagates the request and trace IDs of the request context, retries the
+	// idempotent requests failing with a network error or a 5xx and measures every attempt.
+	clientTransport struct {
+		base        http.RoundTripper
+		timeout     time.Duration
+		maxRetries  int
+		backoff     time.Duration
+		traceHeader string
+		traceID     func(ctx context.Context) string
+		registerer  prometheus.Registerer
+		totalCount  *prometheus.CounterVec
+		duration    *prometheus.HistogramVec
+	}
+)
+
+var idempotentMethods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodOptions: true,
+	http.MethodTrace:   true,
+	http.MethodPut:     true,
+	http.MethodDelete:  true,
+}
+
+// NewHTTPClient returns a client for the outbound calls of the services: it times out after 30s,
+// retries transient failures when configured, forwards the request ID of the request context in
+// the X-Request-ID header and measures the calls by method and host in app_client_requests_total
+// and app_client_request_duration_seconds, on prometheus.DefaultRegisterer by default.
+func NewHTTPClient(options ...ClientOption) *http.Client {
+	base := http.DefaultTransport.(*http.Transport).Clone()
+	base.DialContext = (&net.Dialer{Timeout: 5 * time.Second, KeepAlive: 30 * time.Second}).DialContext
+	base.TLSHandshakeTimeout = 5 * time.Second
+	base.ResponseHeaderTimeout = 10 * time.Second
+
+	transport := &clientTransport{
+		base:       base,
+		timeout:    30 * time.Second,
+		backoff:    100 * time.Millisecond,
+		registerer: prometheus.DefaultRegisterer,
+	}
+	for _, option := range options {
+		option(transport)
+	}
+
+	transport.totalCount = registerCollector(transport.registerer, prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "app_client_requests_total",
+			Help: "A counter for outbound requests, retries included.",
+		},
+		[]string{"code", "method", "host"},
+	)).(*prometheus.CounterVec)
+	transport.duration = registerCollector(transport.registerer, prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "app_client_request_duration_seconds",
+			Help:    "A histogram of outbound request latencies, retries included.",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"code", "method", "host"},
+	)).(*prometheus.HistogramVec)
+
+	return &http.Client{
+		Transport: transport,
+		Timeout:   transport.timeout,
+	}
+}
+
+// WithClientTimeout bounds the whole call, retries included, 30s by default.
+func WithClientTimeout(d time.Duration) ClientOption {
+	return func(transport *clientTransport) {
+		transport.timeout = d
+	}
+}
+
+// WithClientRetries retries up to maxRetries times the idempotent requests failing with a network
+// error or a 5xx, waiting backoff then twice as long on every retry. Requests whose body cannot
+// be replayed are not retried.
+func WithClientRetries(maxRetries int, backoff time.Duration) ClientOption {
+	return func(transport *clientTransport) {
+		transport.maxRetries = maxRetries
+		transport.backoff = backoff
+	}
+}
+
+// WithClientTracePropagation sets the header to the trace ID returned by traceID for the request
+// context, when not empty, such as the one given to WithExemplars.
+func WithClientTracePropagation(header string, traceID func(ctx context.Context) string) ClientOption {
+	return func(transport *clientTransport) {
+		transport.traceHeader = header
+		transport.traceID = traceID
+	}
+}
+
+// WithClientTransport sets the transport the calls are made with.
+func WithClientTransport(base http.RoundTripper) ClientOption {
+	return func(transport *clientTransport) {
+		transport.base = base
+	}
+}
+
+func WithClientRegisterer(registerer prometheus.Registerer) ClientOption {
+	return func(transport *clientTransport) {
+		transport.registerer = registerer
+	}
+}
+
+// registerCollector registers the collector, or returns the one already registered so that several
+// clients share the same metrics.
+func registerCollector(registerer prometheus.Registerer, collector prometheus.Collector) prometheus.Collector {
+	if err := registerer.Register(collector); err != nil {
+		var registered prometheus.AlreadyRegisteredError
+		if errors.As(err, &registered) {
+			return registered.ExistingCollector
+		}
+		panic(err)
+	}
+
+	return collector
+}
+
+func (transport *clientTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	ctx := r.Context()
+	r = r.Clone(ctx)
+	if id := kurin.RequestID(ctx); id != "" && r.Header.Get(requestIDHeader) == "" {
+		r.Header.Set(requestIDHeader, id)
+	}
+	if transport.traceID != nil {
+		if traceID := transport.traceID(ctx); traceID != "" {
+			r.Header.Set(transport.traceHeader, traceID)
+		}
+	}
+
+	retryable := idempotentMethods[r.Method] && (r.Body == nil || r.Body == http.NoBody || r.GetBody != nil)
+	backoff := transport.backoff
+	for attempt := 0; ; attempt++ {
+		res, err := transport.roundTrip(r)
+		if !retryable || attempt >= transport.maxRetries || (err == nil && res.StatusCode < 500) {
+			return res, err
+		}
+		if err == nil {
+			io.Copy(io.Discard, res.Body)
+			res.Body.Close()
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		}
+		backoff *= 2
+
+		if r.GetBody != nil {
+			if r.Body, err = r.GetBody(); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+func (transport *clientTransport) roundTrip(r *http.Request) (*http.Response, error) {
+	now := time.Now()
+	res, err := transport.base.RoundTrip(r)
+
+	code := "error"
+	if err == nil {
+		code = strconv.Itoa(res.StatusCode)
+	}
+	transport.totalCount.WithLabelValues(code, r.Method, r.URL.Host).Inc()
+	transport.duration.WithLabelValues(code, r.Method, r.URL.Host).Observe(time.Since(now).Seconds())
+
+	return res, err
+}
@@ -0,0 +1,82 @@
+package http
+
+import "time"
+
+// defaultClientClosedCode labels the requests abandoned by their client, following nginx.
+const defaultClientClosedCode = 499
+
+type (
+	// Config holds the serializable settings of the adapter, so it can be loaded from a
+	// configuration file (JSON, YAML) or the environment. Start from DefaultConfig so that
+	// missing settings keep their default: an empty internal path disables the endpoint. The
+	// liveness and readiness endpoints are disabled by default, not to shadow routes of the
+	// router on their paths, set LivePath and ReadyPath to mount them.
+	//
+	// ReadTimeout bounds the reading of a whole request, body included, while ReadHeaderTimeout
+	// only bounds its headers. The latter protects against clients trickling their headers in,
+	// and stays short when ReadTimeout is raised for large uploads.
+	Config struct {
+		Name              string        `json:"name" yaml:"name"`
+		Host              string        `json:"host" yaml:"host"`
+		Port              int           `json:"port" yaml:"port"`
+		Version           string        `json:"version" yaml:"version"`
+		HealthPath        string        `json:"health_path" yaml:"health_path"`
+		LivePath          string        `json:"live_path" yaml:"live_path"`
+		ReadyPath         string        `json:"ready_path" yaml:"ready_path"`
+		VersionPath       string        `json:"version_path" yaml:"version_path"`
+		MetricsPath       string        `json:"metrics_path" yaml:"metrics_path"`
+		DisableMetrics    bool          `json:"disable_metrics" yaml:"disable_metrics"`
+		AdminPath         string        `json:"admin_path" yaml:"admin_path"`
+		ClientClosedCode  int           `json:"client_closed_code" yaml:"client_closed_code"`
+		ReadTimeout       Duration      `json:"read_timeout" yaml:"read_timeout"`
+		ReadHeaderTimeout Duration      `json:"read_header_timeout" yaml:"read_header_timeout"`
+		WriteTimeout      Duration      `json:"write_timeout" yaml:"write_timeout"`
+		PreStopDelay      Duration      `json:"pre_stop_delay" yaml:"pre_stop_delay"`
+		ShutdownTimeout   Duration      `json:"shutdown_timeout" yaml:"shutdown_timeout"`
+		Middlewares       MiddlewareSet `json:"middlewares" yaml:"middlewares"`
+	}
+
+	// Duration is a time.Duration read from and written to text as "10s", "1m30s"...
+	Duration time.Duration
+)
+
+func DefaultConfig() Config {
+	return Config{
+		HealthPath:        "/health",
+		VersionPath:       "/version",
+		MetricsPath:       "/metrics",
+		ClientClosedCode:  defaultClientClosedCode,
+		ReadTimeout:       Duration(10 * time.Second),
+		ReadHeaderTimeout: Duration(5 * time.Second),
+		WriteTimeout:      Duration(10 * time.Second),
+	}
+}
+
+// UnmarshalYAML reads the duration from YAML, as gopkg.in/yaml.v2 does not use UnmarshalText.
+func (d *Duration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var text string
+	if err := unmarshal(&text); err != nil {
+		return err
+	}
+
+	return d.UnmarshalText([]byte(text))
+}
+
+// MarshalYAML writes the duration to YAML as text, like MarshalText.
+func (d Duration) MarshalYAML() (interface{}, error) {
+	return time.Duration(d).String(), nil
+}
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+func (d *Duration) UnmarshalText(text []byte) error {
+	duration, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = Duration(duration)
+
+	return nil
+}
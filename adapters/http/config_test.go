@@ -0,0 +1,51 @@
+package http
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDurationUnmarshalYAML(t *testing.T) {
+	var d Duration
+	err := d.UnmarshalYAML(func(v interface{}) error {
+		*v.(*string) = "1m30s"
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if time.Duration(d) != 90*time.Second {
+		t.Errorf("got %s, want 1m30s", time.Duration(d))
+	}
+
+	if err := d.UnmarshalYAML(func(v interface{}) error {
+		*v.(*string) = "soon"
+		return nil
+	}); err == nil {
+		t.Error("expected an error for an invalid duration")
+	}
+
+	text, err := Duration(10 * time.Second).MarshalYAML()
+	if err != nil || text != "10s" {
+		t.Errorf("got %v, %v, want 10s", text, err)
+	}
+}
+
+func TestMiddlewareSetText(t *testing.T) {
+	var set MiddlewareSet
+	if err := set.UnmarshalText([]byte("etag, request_id,recovery")); err != nil {
+		t.Fatal(err)
+	}
+	if set != MiddlewareETag|MiddlewareRequestID|MiddlewareRecovery {
+		t.Errorf("got set %b", set)
+	}
+
+	text, err := set.MarshalText()
+	if err != nil || string(text) != "recovery,request_id,etag" {
+		t.Errorf("got %q, %v, want the canonical order", text, err)
+	}
+
+	if err := set.UnmarshalText([]byte("access_log")); err == nil {
+		t.Error("expected an error for an unknown middleware")
+	}
+}
@@ -0,0 +1,98 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const shutdownProgressInterval = 5 * time.Second
+
+// connTracker follows the connections of the server through its ConnState hook, and the requests in
+// flight, to report the shutdown progress.
+type connTracker struct {
+	mu       sync.Mutex
+	conns    map[net.Conn]http.ConnState
+	inFlight int64
+}
+
+func (tracker *connTracker) track(conn net.Conn, state http.ConnState) {
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+
+	switch state {
+	case http.StateHijacked, http.StateClosed:
+		delete(tracker.conns, conn)
+	default:
+		if tracker.conns == nil {
+			tracker.conns = make(map[net.Conn]http.ConnState)
+		}
+		tracker.conns[conn] = state
+	}
+}
+
+func (tracker *connTracker) openConns() int {
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+
+	return len(tracker.conns)
+}
+
+func (tracker *connTracker) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&tracker.inFlight, 1)
+		defer atomic.AddInt64(&tracker.inFlight, -1)
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// drain shuts the server down gracefully, logging every few seconds how many requests are still
+// in flight, and forces the remaining connections closed once ctx is done or if it fails.
+func (adapter *Adapter) drain(ctx context.Context) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- adapter.srv.Shutdown(ctx)
+	}()
+
+	ticker := time.NewTicker(shutdownProgressInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case err := <-done:
+			if err != nil {
+				adapter.forceClose(err)
+			}
+			return err
+		case <-ticker.C:
+			adapter.logger.Info(fmt.Sprintf(
+				"Shutting down the http server, %d requests in flight on %d connections...",
+				atomic.LoadInt64(&adapter.conns.inFlight),
+				adapter.conns.openConns(),
+			))
+		}
+	}
+}
+
+// forceClose closes the connections left by a failed graceful shutdown, logging why it failed.
+func (adapter *Adapter) forceClose(err error) {
+	conns := adapter.conns.openConns()
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		adapter.logger.Warn(fmt.Sprintf("Graceful shutdown timed out, forcing %d connections closed", conns))
+	case errors.Is(err, context.Canceled):
+		adapter.logger.Warn(fmt.Sprintf("Graceful shutdown cancelled, forcing %d connections closed", conns))
+	default:
+		adapter.logger.Error(fmt.Sprintf("Graceful shutdown failed: %s, forcing %d connections closed", err, conns))
+	}
+
+	if err := adapter.srv.Close(); err != nil {
+		adapter.logger.Error(fmt.Sprintf("Unable to force the http server closed: %s", err))
+	}
+}
@@ -0,0 +1,70 @@
+package http
+
+import (
+	"mime"
+	"net/http"
+	"strings"
+)
+
+const (
+	contentTypeNone  = "none"
+	contentTypeOther = "other"
+)
+
+var defaultMetricsContentTypes = []string{
+	"application/json",
+	"application/xml",
+	"text/xml",
+	"application/x-www-form-urlencoded",
+	"multipart/form-data",
+	"application/octet-stream",
+	"text/plain",
+	"text/html",
+}
+
+func contentTypeMiddleware(types []string) Middleware {
+	allowed := make(map[string]bool, len(types))
+	for _, t := range types {
+		allowed[strings.ToLower(t)] = true
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !hasBody(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+			if err != nil || !allowed[mediaType] {
+				writeError(w, http.StatusUnsupportedMediaType, "unsupported_media_type")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// metricsContentType buckets the media type of a Content-Type header into the known types, so that
+// clients cannot make the cardinality of the content type metrics grow.
+func metricsContentType(header string, known map[string]bool) string {
+	if header == "" {
+		return contentTypeNone
+	}
+	mediaType, _, err := mime.ParseMediaType(header)
+	if err != nil || !known[mediaType] {
+		return contentTypeOther
+	}
+
+	return mediaType
+}
+
+func hasBody(r *http.Request) bool {
+	switch r.Method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch:
+		return r.ContentLength != 0
+	}
+
+	return false
+}
@@ -1,17 +1,71 @@
 package http
 
-import "net/http"
+import (
+	"net/http"
+	"time"
 
+	"github.com/maxperrimond/kurin"
+)
+
+// customResponseWriter observes the response without buffering it, sharing the header of the
+// wrapped writer: trailers declared by the Trailer header, or set with http.TrailerPrefix, once
+// the body is written pass through as they do without it. The buffering middlewares (WithETag,
+// WithSingleflight, WithIdempotency) keep them as trailers too.
 type customResponseWriter struct {
 	http.ResponseWriter
 	statusCode int
+	size       int
+	head       bool
+	firstWrite time.Time
+	clock      kurin.Clock
+	err        error
 }
 
 func NewCustomResponseWriter(w http.ResponseWriter) *customResponseWriter {
-	return &customResponseWriter{w, http.StatusOK}
+	return &customResponseWriter{ResponseWriter: w, statusCode: http.StatusOK, clock: kurin.SystemClock}
 }
 
 func (lrw *customResponseWriter) WriteHeader(code int) {
 	lrw.statusCode = code
+	lrw.wrote()
 	lrw.ResponseWriter.WriteHeader(code)
 }
+
+func (lrw *customResponseWriter) Write(b []byte) (int, error) {
+	lrw.wrote()
+	n, err := lrw.ResponseWriter.Write(b)
+	// The server discards the body of HEAD responses, still reporting it as written.
+	if !lrw.head {
+		lrw.size += n
+	}
+	if err != nil && lrw.err == nil {
+		lrw.err = err
+	}
+
+	return n, err
+}
+
+func (lrw *customResponseWriter) Flush() {
+	if f, ok := lrw.ResponseWriter.(http.Flusher); ok {
+		lrw.wrote()
+		f.Flush()
+	}
+}
+
+// Err returns the first error writing the body, e.g. when the client went away mid-response, the
+// size only counting the bytes written before it.
+func (lrw *customResponseWriter) Err() error {
+	return lrw.err
+}
+
+// wrote keeps the time of the first write, headers included, to measure the time to first byte.
+func (lrw *customResponseWriter) wrote() {
+	if lrw.firstWrite.IsZero() {
+		lrw.firstWrite = lrw.clock.Now()
+	}
+}
+
+// Unwrap gives http.ResponseController access to the wrapped writer.
+func (lrw *customResponseWriter) Unwrap() http.ResponseWriter {
+	return lrw.ResponseWriter
+}
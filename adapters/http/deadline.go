@@ -0,0 +1,58 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// deadlineWriter tells whether the handler wrote anything, headers included.
+type deadlineWriter struct {
+	http.ResponseWriter
+	wrote bool
+}
+
+// deadlineMiddleware bounds the context of the requests by d, answering a 503 when it expired
+// before the handler wrote anything.
+func deadlineMiddleware(d time.Duration) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithTimeout(r.Context(), d)
+			defer cancel()
+
+			dw := &deadlineWriter{ResponseWriter: w}
+			next.ServeHTTP(dw, r.WithContext(ctx))
+			if !dw.wrote && ctx.Err() == context.DeadlineExceeded {
+				writeError(w, http.StatusServiceUnavailable, "deadline_exceeded")
+			}
+		})
+	}
+}
+
+// deadlineExceeded tells whether err comes from the deadline of the request having expired.
+func deadlineExceeded(r *http.Request, err error) bool {
+	return r.Context().Err() == context.DeadlineExceeded && errors.Is(err, context.DeadlineExceeded)
+}
+
+func (w *deadlineWriter) WriteHeader(code int) {
+	w.wrote = true
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *deadlineWriter) Write(b []byte) (int, error) {
+	w.wrote = true
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *deadlineWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		w.wrote = true
+		f.Flush()
+	}
+}
+
+// Unwrap gives http.ResponseController access to the wrapped writer.
+func (w *deadlineWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
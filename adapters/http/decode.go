@@ -0,0 +1,85 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+)
+
+type (
+	DecodeErrorKind string
+
+	DecodeError struct {
+		Kind DecodeErrorKind
+		Err  error
+	}
+)
+
+const (
+	MalformedJSON DecodeErrorKind = "malformed_json"
+	BodyTooLarge  DecodeErrorKind = "body_too_large"
+	UnknownField  DecodeErrorKind = "unknown_field"
+
+	MalformedMultipart DecodeErrorKind = "malformed_multipart"
+	TooManyParts       DecodeErrorKind = "too_many_parts"
+	FileTooLarge       DecodeErrorKind = "file_too_large"
+)
+
+// DecodeJSON decodes the single JSON value of the request body into dst, refusing bodies above
+// maxBytes and fields unknown to dst. The returned *DecodeError renders as a 400, or a 413 for
+// a too large body, through WriteError.
+func DecodeJSON(w http.ResponseWriter, r *http.Request, dst interface{}, maxBytes int64) error {
+	r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(dst); err != nil {
+		return newDecodeError(err)
+	}
+
+	if err := decoder.Decode(&struct{}{}); err != io.EOF {
+		if err == nil {
+			err = errors.New("body must only contain a single JSON value")
+		}
+		return newDecodeError(err)
+	}
+
+	return nil
+}
+
+func newDecodeError(err error) *DecodeError {
+	var maxBytesErr *http.MaxBytesError
+	switch {
+	case errors.As(err, &maxBytesErr):
+		return &DecodeError{BodyTooLarge, err}
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		return &DecodeError{UnknownField, err}
+	case err == io.EOF:
+		return &DecodeError{MalformedJSON, errors.New("body must not be empty")}
+	}
+
+	return &DecodeError{MalformedJSON, err}
+}
+
+func (err *DecodeError) Error() string {
+	return err.Err.Error()
+}
+
+func (err *DecodeError) Unwrap() error {
+	return err.Err
+}
+
+func (err *DecodeError) StatusCode() int {
+	switch err.Kind {
+	case BodyTooLarge, TooManyParts, FileTooLarge:
+		return http.StatusRequestEntityTooLarge
+	}
+
+	return http.StatusBadRequest
+}
+
+func (err *DecodeError) Category() string {
+	return string(err.Kind)
+}
@@ -0,0 +1,57 @@
+package http
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"strings"
+)
+
+type decompressedBody struct {
+	io.Reader
+	body         io.Closer
+	decompressor io.Closer
+}
+
+func decompressionMiddleware(maxBytes int64) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			encoding := strings.ToLower(strings.TrimSpace(r.Header.Get("Content-Encoding")))
+			if encoding == "" || encoding == "identity" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			var (
+				reader io.ReadCloser
+				err    error
+			)
+			switch encoding {
+			case "gzip", "x-gzip":
+				reader, err = gzip.NewReader(r.Body)
+			case "deflate":
+				reader, err = zlib.NewReader(r.Body)
+			default:
+				writeError(w, http.StatusUnsupportedMediaType, "unsupported_encoding")
+				return
+			}
+			if err != nil {
+				writeError(w, http.StatusBadRequest, "malformed_encoding")
+				return
+			}
+
+			r.Body = http.MaxBytesReader(w, &decompressedBody{reader, r.Body, reader}, maxBytes)
+			r.Header.Del("Content-Encoding")
+			r.Header.Del("Content-Length")
+			r.ContentLength = -1
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func (body *decompressedBody) Close() error {
+	body.decompressor.Close()
+
+	return body.body.Close()
+}
@@ -0,0 +1,84 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/maxperrimond/kurin"
+)
+
+type (
+	// HandlerFunc is a handler returning its error instead of rendering it, the error being
+	// answered by WriteError and counted by the HandlerErrorRecorder metrics recorders.
+	HandlerFunc func(w http.ResponseWriter, r *http.Request) error
+
+	handlerErrorKey struct{}
+
+	errorResponse struct {
+		Error   string `json:"error"`
+		Status  int    `json:"status"`
+		Message string `json:"message,omitempty"`
+	}
+)
+
+func (fn HandlerFunc) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := fn(w, r); err != nil {
+		if deadlineExceeded(r, err) {
+			// The work was cut by the deadline of the request, not broken.
+			if kind, ok := r.Context().Value(handlerErrorKey{}).(*string); ok {
+				*kind = "deadline_exceeded"
+			}
+			writeError(w, http.StatusServiceUnavailable, "deadline_exceeded")
+			return
+		}
+		if kind, ok := r.Context().Value(handlerErrorKey{}).(*string); ok {
+			*kind = errorCategory(err)
+		}
+		WriteError(w, err)
+	}
+}
+
+// WriteError answers the JSON error envelope for err, using its status and category when it is
+// a kurin.HTTPError and a 500 without details otherwise.
+func WriteError(w http.ResponseWriter, err error) {
+	var httpErr kurin.HTTPError
+	if errors.As(err, &httpErr) {
+		writeErrorResponse(w, &errorResponse{
+			Error:   httpErr.Category(),
+			Status:  httpErr.StatusCode(),
+			Message: httpErr.Error(),
+		})
+		return
+	}
+
+	writeError(w, http.StatusInternalServerError, "internal_error")
+}
+
+func errorCategory(err error) string {
+	var httpErr kurin.HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.Category()
+	}
+
+	return "internal_error"
+}
+
+func errorHandler(status int, code string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, status, code)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, code string) {
+	writeErrorResponse(w, &errorResponse{
+		Error:  code,
+		Status: status,
+	})
+}
+
+func writeErrorResponse(w http.ResponseWriter, response *errorResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(response.Status)
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,49 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+type conflictError struct{}
+
+func (conflictError) Error() string    { return "user already exists" }
+func (conflictError) StatusCode() int  { return http.StatusConflict }
+func (conflictError) Category() string { return "conflict" }
+
+func TestHandlerErrorsTotal(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	router := mux.NewRouter()
+	router.Handle("/users/{id}", HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		if r.Method == http.MethodPut {
+			return conflictError{}
+		}
+		return errors.New("database down")
+	}))
+	adapter := newTestAdapter(t, router, WithRegistry(registry))
+
+	for _, method := range []string{http.MethodPut, http.MethodPut, http.MethodGet} {
+		w := httptest.NewRecorder()
+		adapter.srv.Handler.ServeHTTP(w, httptest.NewRequest(method, "/users/1", nil))
+		if want := map[string]int{http.MethodPut: http.StatusConflict, http.MethodGet: http.StatusInternalServerError}[method]; w.Code != want {
+			t.Errorf("%s: got status %d, want %d", method, w.Code, want)
+		}
+	}
+
+	expected := `
+# HELP app_handler_errors_total A counter for errors returned by the handlers, by kind.
+# TYPE app_handler_errors_total counter
+app_handler_errors_total{handler="/users/{id}",kind="conflict"} 2
+app_handler_errors_total{handler="/users/{id}",kind="internal_error"} 1
+`
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(expected), "app_handler_errors_total"); err != nil {
+		t.Error(err)
+	}
+}
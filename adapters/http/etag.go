@@ -0,0 +1,78 @@
+package http
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+func etagMiddleware(maxBytes int) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet && r.Method != http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			bw := &bufferingWriter{w: w, header: http.Header{}, status: http.StatusOK, maxBytes: maxBytes}
+			if r.Method == http.MethodHead {
+				// The ETag of a HEAD response is the one of the GET response, whose body the
+				// handler may not write for HEAD and the server discards anyway.
+				get := r.Clone(r.Context())
+				get.Method = http.MethodGet
+				next.ServeHTTP(bw, get)
+			} else {
+				next.ServeHTTP(bw, r)
+			}
+			if bw.passthrough {
+				return
+			}
+
+			trailers := splitTrailers(bw.header)
+			for key, values := range bw.header {
+				w.Header()[key] = values
+			}
+			if bw.status != http.StatusOK {
+				w.WriteHeader(bw.status)
+				w.Write(bw.body)
+				writeTrailers(w, trailers)
+				return
+			}
+
+			etag := w.Header().Get("ETag")
+			if etag == "" {
+				sum := sha256.Sum256(bw.body)
+				etag = `"` + hex.EncodeToString(sum[:16]) + `"`
+				w.Header().Set("ETag", etag)
+			}
+			if etagMatch(r.Header.Get("If-None-Match"), etag) {
+				w.Header().Del("Content-Length")
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+
+			w.WriteHeader(bw.status)
+			w.Write(bw.body)
+			writeTrailers(w, trailers)
+		})
+	}
+}
+
+// etagMatch reports whether the If-None-Match header matches the ETag, using the weak comparison
+// required for it.
+func etagMatch(ifNoneMatch string, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,57 @@
+package http
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func TestETagOfHead(t *testing.T) {
+	router := mux.NewRouter()
+	router.HandleFunc("/users/1", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodHead {
+			w.Write([]byte(`{"id":1}`))
+		}
+	})
+	adapter := newTestAdapter(t, router, WithETag(1024))
+
+	etags := map[string]string{}
+	for _, method := range []string{http.MethodGet, http.MethodHead} {
+		w := httptest.NewRecorder()
+		adapter.srv.Handler.ServeHTTP(w, httptest.NewRequest(method, "/users/1", nil))
+		etags[method] = w.Header().Get("ETag")
+	}
+	if etags[http.MethodGet] == "" || etags[http.MethodHead] != etags[http.MethodGet] {
+		t.Errorf("got ETags %v, want the same for GET and HEAD", etags)
+	}
+}
+
+func TestETagStreamsFlushedResponses(t *testing.T) {
+	flushed := make(chan struct{})
+	router := mux.NewRouter()
+	router.HandleFunc("/events", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("first\n"))
+		w.(http.Flusher).Flush()
+		<-flushed
+		w.Write([]byte("second\n"))
+	})
+	adapter := newTestAdapter(t, router, WithETag(1024), WithSingleflight(1024))
+	startTestAdapter(t, adapter)
+
+	resp, err := http.Get("http://" + adapter.listener.Addr().String() + "/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	first := make([]byte, len("first\n"))
+	if _, err := io.ReadFull(resp.Body, first); err != nil || string(first) != "first\n" {
+		t.Fatalf("got %q, %v before the end of the response, want the flushed part", first, err)
+	}
+	close(flushed)
+	if resp.Header.Get("ETag") != "" {
+		t.Errorf("got ETag %q on a streamed response", resp.Header.Get("ETag"))
+	}
+}
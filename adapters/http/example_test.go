@@ -0,0 +1,46 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/gorilla/mux"
+	"github.com/maxperrimond/kurin"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func ExampleAdapter_OnFailure() {
+	adapter := NewHTTPAdapter(mux.NewRouter(), nil, "127.0.0.1", 0, "example", kurin.NewStdLogger(io.Discard, kurin.LevelError),
+		WithRegistry(prometheus.NewRegistry())).(*Adapter)
+
+	adapter.OnFailure(errors.New("redis down"))
+	fmt.Println(adapter.IsHealthy())
+
+	adapter.OnFailure(nil)
+	fmt.Println(adapter.IsHealthy())
+	// Output:
+	// false
+	// true
+}
+
+func ExampleAdapter_MarkUnhealthy() {
+	adapter := NewHTTPAdapter(mux.NewRouter(), nil, "127.0.0.1", 0, "example", kurin.NewStdLogger(io.Discard, kurin.LevelError),
+		WithRegistry(prometheus.NewRegistry())).(*Adapter)
+	health := func() {
+		w := httptest.NewRecorder()
+		adapter.srv.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+		fmt.Println(w.Code, w.Body.String())
+	}
+
+	adapter.MarkUnhealthy(errors.New("maintenance"))
+	health()
+
+	adapter.MarkHealthy()
+	health()
+	// Output:
+	// 503 maintenance
+	// 204
+}
@@ -0,0 +1,61 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+type (
+	failureResponse struct {
+		Time  time.Time `json:"time"`
+		Error string    `json:"error"`
+	}
+
+	// failureHistory is a ring buffer of the last failures reported to the adapter.
+	failureHistory struct {
+		mu       sync.Mutex
+		failures []failureResponse
+		next     int
+		full     bool
+	}
+)
+
+func newFailureHistory(size int) *failureHistory {
+	return &failureHistory{failures: make([]failureResponse, size)}
+}
+
+func (history *failureHistory) add(t time.Time, err error) {
+	history.mu.Lock()
+	defer history.mu.Unlock()
+
+	history.failures[history.next] = failureResponse{Time: t, Error: err.Error()}
+	history.next = (history.next + 1) % len(history.failures)
+	if history.next == 0 {
+		history.full = true
+	}
+}
+
+// list returns the failures, the most recent first.
+func (history *failureHistory) list() []failureResponse {
+	history.mu.Lock()
+	defer history.mu.Unlock()
+
+	count := history.next
+	if history.full {
+		count = len(history.failures)
+	}
+	failures := make([]failureResponse, 0, count)
+	for i := 1; i <= count; i++ {
+		failures = append(failures, history.failures[(history.next-i+len(history.failures))%len(history.failures)])
+	}
+
+	return failures
+}
+
+func (adapter *Adapter) failuresHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(adapter.failures.list())
+}
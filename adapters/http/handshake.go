@@ -0,0 +1,122 @@
+package http
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"sync"
+	"time"
+)
+
+const defaultHandshakeTimeout = 10 * time.Second
+
+// handshakeListener performs the TLS handshakes of the accepted connections itself, at most as many
+// at once as it has slots, before handing them to the server. A connection waiting for a slot, or
+// whose handshake takes, longer than the timeout is closed.
+type handshakeListener struct {
+	net.Listener
+	config  *tls.Config
+	slots   chan struct{}
+	timeout time.Duration
+	conns   chan net.Conn
+	errs    chan error
+	done    chan struct{}
+	once    sync.Once
+}
+
+func newHandshakeListener(listener net.Listener, config *tls.Config, n int, timeout time.Duration) *handshakeListener {
+	handshakes := &handshakeListener{
+		Listener: listener,
+		config:   config,
+		slots:    make(chan struct{}, n),
+		timeout:  timeout,
+		conns:    make(chan net.Conn),
+		errs:     make(chan error),
+		done:     make(chan struct{}),
+	}
+	go handshakes.serve()
+
+	return handshakes
+}
+
+func (listener *handshakeListener) serve() {
+	for {
+		conn, err := listener.Listener.Accept()
+		if err != nil {
+			select {
+			case listener.errs <- err:
+			case <-listener.done:
+				return
+			}
+			if temporary, ok := err.(interface{ Temporary() bool }); ok && temporary.Temporary() {
+				continue
+			}
+			return
+		}
+		go listener.handshake(conn)
+	}
+}
+
+func (listener *handshakeListener) handshake(conn net.Conn) {
+	timer := time.NewTimer(listener.timeout)
+	defer timer.Stop()
+	select {
+	case listener.slots <- struct{}{}:
+	case <-timer.C:
+		conn.Close()
+		return
+	case <-listener.done:
+		conn.Close()
+		return
+	}
+
+	tlsConn := tls.Server(conn, listener.config)
+	ctx, cancel := context.WithTimeout(context.Background(), listener.timeout)
+	err := tlsConn.HandshakeContext(ctx)
+	cancel()
+	<-listener.slots
+	if err != nil {
+		conn.Close()
+		return
+	}
+
+	select {
+	case listener.conns <- tlsConn:
+	case <-listener.done:
+		tlsConn.Close()
+	}
+}
+
+func (listener *handshakeListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-listener.conns:
+		return conn, nil
+	case err := <-listener.errs:
+		return nil, err
+	case <-listener.done:
+		return nil, net.ErrClosed
+	}
+}
+
+func (listener *handshakeListener) Close() error {
+	listener.once.Do(func() {
+		close(listener.done)
+	})
+
+	return listener.Listener.Close()
+}
+
+// handshakeTimeout bounds the handshakes like the server does, by its shortest read or write timeout.
+func (adapter *Adapter) handshakeTimeout() time.Duration {
+	timeout := time.Duration(0)
+	for _, d := range []time.Duration{adapter.srv.ReadHeaderTimeout, adapter.srv.ReadTimeout, adapter.srv.WriteTimeout} {
+		if d > 0 && (timeout == 0 || d < timeout) {
+			timeout = d
+		}
+	}
+	if timeout == 0 {
+		return defaultHandshakeTimeout
+	}
+
+	return timeout
+}
@@ -0,0 +1,209 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/maxperrimond/kurin"
+)
+
+const (
+	healthStatusHealthy   = "healthy"
+	healthStatusDegraded  = "degraded"
+	healthStatusUnhealthy = "unhealthy"
+
+	checkStatusOK        = "ok"
+	checkStatusFailing   = "failing"
+	checkStatusTimeout   = "timeout"
+	checkStatusCancelled = "cancelled"
+)
+
+type (
+	healthResponse struct {
+		Status string                          `json:"status"`
+		Error  string                          `json:"error,omitempty"`
+		Checks map[string]*healthCheckResponse `json:"checks"`
+	}
+
+	healthCheckResult struct {
+		index   int
+		err     error
+		latency time.Duration
+	}
+
+	healthCheckResponse struct {
+		Status   string   `json:"status"`
+		Critical bool     `json:"critical"`
+		Error    string   `json:"error,omitempty"`
+		Latency  Duration `json:"latency"`
+	}
+)
+
+func (adapter *Adapter) healthHandler(w http.ResponseWriter, r *http.Request) {
+	adapter.mu.RLock()
+	stopping, healthy, lastError := adapter.stopping, adapter.healthy, adapter.lastError
+	adapter.mu.RUnlock()
+
+	if checks := adapter.checks(); len(checks) > 0 {
+		adapter.healthReport(w, r, checks, stopping, healthy, lastError)
+		return
+	}
+
+	switch {
+	case stopping:
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte("shutting down"))
+	case !healthy:
+		w.WriteHeader(http.StatusServiceUnavailable)
+		if lastError != nil {
+			w.Write([]byte(lastError.Error()))
+		}
+	default:
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// checks returns the checks of the health registry: the ones of WithHealthCheck, or all the ones
+// of the application once it gave its registry.
+func (adapter *Adapter) checks() []kurin.HealthCheck {
+	adapter.mu.RLock()
+	defer adapter.mu.RUnlock()
+
+	return adapter.healthRegistry.Checks()
+}
+
+// HealthChecks returns the checks of WithHealthCheck, added to the health registry of the
+// application the adapter is given to.
+func (adapter *Adapter) HealthChecks() []kurin.HealthCheck {
+	return adapter.ownHealthChecks
+}
+
+// UseHealthRegistry runs the checks of the registry, those of the application, on the health
+// probes instead of the ones of WithHealthCheck only, unless WithoutAppHealthChecks is set.
+func (adapter *Adapter) UseHealthRegistry(registry *kurin.HealthRegistry) {
+	if adapter.ignoreAppHealthChecks {
+		return
+	}
+
+	adapter.mu.Lock()
+	defer adapter.mu.Unlock()
+
+	adapter.healthRegistry = registry
+}
+
+// healthReport runs the health checks and answers their results as JSON: a failing critical
+// check makes the adapter unhealthy (503) while a failing non critical one only degrades it.
+func (adapter *Adapter) healthReport(w http.ResponseWriter, r *http.Request, checks []kurin.HealthCheck, stopping bool, healthy bool, lastError error) {
+	response := &healthResponse{
+		Status: healthStatusHealthy,
+		Checks: make(map[string]*healthCheckResponse, len(checks)),
+	}
+
+	failing, degraded := adapter.evaluateHealthChecks(r.Context(), checks, response.Checks)
+	if failing {
+		response.Status = healthStatusUnhealthy
+	} else if degraded {
+		response.Status = healthStatusDegraded
+	}
+
+	switch {
+	case stopping:
+		response.Status = healthStatusUnhealthy
+		response.Error = "shutting down"
+	case !healthy:
+		response.Status = healthStatusUnhealthy
+		if lastError != nil {
+			response.Error = lastError.Error()
+		}
+	}
+
+	status := http.StatusOK
+	if response.Status == healthStatusUnhealthy {
+		status = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
+}
+
+// evaluateHealthChecks runs the checks, adding their results to responses, and returns whether a
+// critical check failed, and whether a non critical one did, which the app_health_degraded metric
+// reports.
+func (adapter *Adapter) evaluateHealthChecks(ctx context.Context, checks []kurin.HealthCheck, responses map[string]*healthCheckResponse) (failing bool, degraded bool) {
+	for i, result := range adapter.runHealthChecks(ctx, checks) {
+		check := checks[i]
+		if result.Status != checkStatusOK {
+			if check.Critical {
+				failing = true
+			} else {
+				degraded = true
+			}
+		}
+		responses[check.Name] = result
+	}
+	if adapter.degradedGauge != nil {
+		if degraded {
+			adapter.degradedGauge.Set(1)
+		} else {
+			adapter.degradedGauge.Set(0)
+		}
+	}
+
+	return failing, degraded
+}
+
+// runHealthChecks runs the checks concurrently within the health timeout, if any, timing them. The
+// checks that did not return in time are reported as timing out, and the remaining ones as
+// cancelled once a critical check failed with the fail fast option.
+func (adapter *Adapter) runHealthChecks(ctx context.Context, checks []kurin.HealthCheck) []*healthCheckResponse {
+	var cancel context.CancelFunc
+	if adapter.healthTimeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, adapter.healthTimeout)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+	defer cancel()
+
+	start := adapter.clock.Now()
+	results := make(chan healthCheckResult, len(checks))
+	for i, check := range checks {
+		go func(i int, check kurin.HealthCheck) {
+			err := check.Check(ctx)
+			results <- healthCheckResult{i, err, adapter.clock.Now().Sub(start)}
+		}(i, check)
+	}
+
+	responses := make([]*healthCheckResponse, len(checks))
+	pending := checkStatusTimeout
+wait:
+	for range checks {
+		select {
+		case result := <-results:
+			check := checks[result.index]
+			response := &healthCheckResponse{Status: checkStatusOK, Critical: check.Critical, Latency: Duration(result.latency)}
+			responses[result.index] = response
+			if result.err != nil {
+				response.Status = checkStatusFailing
+				response.Error = result.err.Error()
+				if check.Critical && adapter.healthFailFast {
+					pending = checkStatusCancelled
+					break wait
+				}
+			}
+		case <-ctx.Done():
+			break wait
+		}
+	}
+
+	// The checks still running took at least as long as the probe waited for them.
+	elapsed := Duration(adapter.clock.Now().Sub(start))
+	for i, response := range responses {
+		if response == nil {
+			responses[i] = &healthCheckResponse{Status: pending, Critical: checks[i].Critical, Latency: elapsed}
+		}
+	}
+
+	return responses
+}
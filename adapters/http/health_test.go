@@ -0,0 +1,177 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/maxperrimond/kurin"
+)
+
+func getHealth(t *testing.T, adapter *Adapter) (int, *healthResponse) {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	adapter.srv.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+	response := &healthResponse{}
+	if w.Code != http.StatusNoContent {
+		if err := json.NewDecoder(w.Body).Decode(response); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return w.Code, response
+}
+
+func TestHealthChecksOfTheApplication(t *testing.T) {
+	adapter := newTestAdapter(t, nil, WithHealthCheck("api", false, func(ctx context.Context) error {
+		return errors.New("api down")
+	}))
+	app := kurin.NewApp("test", adapter)
+	if err := app.RegisterHealthCheck("postgres", true, func(ctx context.Context) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+
+	code, response := getHealth(t, adapter)
+	if code != http.StatusOK || response.Status != healthStatusDegraded {
+		t.Fatalf("got %d %s, want 200 degraded", code, response.Status)
+	}
+	if len(response.Checks) != 2 || response.Checks["postgres"].Status != checkStatusOK || response.Checks["api"].Status != checkStatusFailing {
+		t.Errorf("got checks %+v", response.Checks)
+	}
+
+	if err := app.RegisterHealthCheck("redis", true, func(ctx context.Context) error { return errors.New("redis down") }); err != nil {
+		t.Fatal(err)
+	}
+	if code, response := getHealth(t, adapter); code != http.StatusServiceUnavailable || response.Status != healthStatusUnhealthy {
+		t.Errorf("got %d %s, want 503 unhealthy", code, response.Status)
+	}
+}
+
+func TestWithoutAppHealthChecks(t *testing.T) {
+	adapter := newTestAdapter(t, nil, WithoutAppHealthChecks())
+	app := kurin.NewApp("test", adapter)
+	app.RegisterHealthCheck("postgres", true, func(ctx context.Context) error { return errors.New("down") })
+
+	if code, _ := getHealth(t, adapter); code != http.StatusNoContent {
+		t.Errorf("got %d, want 204", code)
+	}
+}
+
+func TestDuplicateHealthCheck(t *testing.T) {
+	check := func(ctx context.Context) error { return nil }
+	adapter := newTestAdapter(t, nil, WithHealthCheck("db", true, check), WithHealthCheck("db", false, check))
+	if err := adapter.OpenContext(context.Background()); !errors.Is(err, kurin.ErrDuplicateHealthCheck) {
+		t.Errorf("got %v, want ErrDuplicateHealthCheck", err)
+	}
+
+	first := newTestAdapter(t, nil, WithHealthCheck("db", true, check))
+	second := newTestAdapter(t, nil, WithHealthCheck("db", true, check))
+	if err := kurin.NewApp("test", first, second).Run(context.Background()); !errors.Is(err, kurin.ErrDuplicateHealthCheck) {
+		t.Errorf("got %v, want ErrDuplicateHealthCheck", err)
+	}
+}
+
+func getReadiness(t *testing.T, adapter *Adapter) (int, *readinessResponse) {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	adapter.srv.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ready", nil))
+	response := &readinessResponse{}
+	if err := json.NewDecoder(w.Body).Decode(response); err != nil {
+		t.Fatal(err)
+	}
+
+	return w.Code, response
+}
+
+func TestReadinessRunsHealthChecks(t *testing.T) {
+	var cacheErr, databaseErr error
+	adapter := newTestAdapter(t, nil,
+		WithProbePaths("/live", "/ready"),
+		WithHealthCheck("cache", false, func(ctx context.Context) error { return cacheErr }),
+		WithHealthCheck("database", true, func(ctx context.Context) error { return databaseErr }),
+	)
+
+	for _, test := range []struct {
+		cacheErr, databaseErr error
+		code                  int
+		status                string
+	}{
+		{nil, nil, http.StatusOK, readinessReady},
+		{errors.New("cache down"), nil, http.StatusOK, readinessDegraded},
+		{nil, errors.New("database down"), http.StatusServiceUnavailable, readinessNotReady},
+	} {
+		cacheErr, databaseErr = test.cacheErr, test.databaseErr
+		code, response := getReadiness(t, adapter)
+		if code != test.code || response.Status != test.status {
+			t.Errorf("cache %v, database %v: got %d %s, want %d %s", test.cacheErr, test.databaseErr, code, response.Status, test.code, test.status)
+		}
+		if len(response.Checks) != 2 {
+			t.Errorf("got checks %+v, want cache and database", response.Checks)
+		}
+	}
+}
+
+func TestFailureOnlyAffectsReadiness(t *testing.T) {
+	adapter := newTestAdapter(t, nil, WithProbePaths("/live", "/ready"))
+	adapter.OnFailure(errors.New("redis down"))
+
+	w := httptest.NewRecorder()
+	adapter.srv.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/live", nil))
+	if w.Code != http.StatusNoContent {
+		t.Errorf("live: got %d, want 204", w.Code)
+	}
+	if code, response := getReadiness(t, adapter); code != http.StatusServiceUnavailable || response.Error != "redis down" {
+		t.Errorf("ready: got %d %q, want 503 redis down", code, response.Error)
+	}
+}
+
+func TestProbesAreOptIn(t *testing.T) {
+	router := mux.NewRouter()
+	router.HandleFunc("/ready", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+	adapter := newTestAdapter(t, router)
+
+	w := httptest.NewRecorder()
+	adapter.srv.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ready", nil))
+	if w.Code != http.StatusTeapot {
+		t.Errorf("got %d, want the route of the router", w.Code)
+	}
+}
+
+func TestReadinessDuringClose(t *testing.T) {
+	adapter := newTestAdapter(t, nil, WithProbePaths("/live", "/ready"))
+	if err := adapter.CloseContext(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if code, response := getReadiness(t, adapter); code != http.StatusServiceUnavailable || response.Error != "shutting down" {
+		t.Errorf("got %d %q, want 503 shutting down without pre-stop delay", code, response.Error)
+	}
+}
+
+func TestPreStopDelay(t *testing.T) {
+	clock := kurin.NewMockClock(time.Now())
+	adapter := newTestAdapter(t, nil, WithProbePaths("/live", "/ready"), WithClock(clock), WithPreStopDelay(time.Minute))
+
+	closed := make(chan error)
+	go func() { closed <- adapter.CloseContext(context.Background()) }()
+	for {
+		// The delay only passes on the clock, once CloseContext waits for it.
+		clock.Add(time.Minute)
+		select {
+		case err := <-closed:
+			if err != nil {
+				t.Fatal(err)
+			}
+			return
+		case <-time.After(time.Millisecond):
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package http
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+func allowedHostsMiddleware(hosts []string) Middleware {
+	exact := make(map[string]bool, len(hosts))
+	var suffixes []string
+	for _, host := range hosts {
+		host = canonicalHost(host)
+		if strings.HasPrefix(host, "*.") {
+			suffixes = append(suffixes, host[1:])
+		} else {
+			exact[host] = true
+		}
+	}
+
+	allowed := func(host string) bool {
+		if exact[host] {
+			return true
+		}
+		for _, suffix := range suffixes {
+			if strings.HasSuffix(host, suffix) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			host, ok := requestHost(r.Host)
+			switch {
+			case !ok:
+				writeError(w, http.StatusBadRequest, "invalid_host")
+			case !allowed(host):
+				writeError(w, http.StatusMisdirectedRequest, "misdirected_request")
+			default:
+				next.ServeHTTP(w, r)
+			}
+		})
+	}
+}
+
+// requestHost returns the canonical host of a Host header, without its port if any.
+func requestHost(header string) (string, bool) {
+	if header == "" {
+		return "", false
+	}
+
+	host := header
+	if strings.LastIndex(header, ":") > strings.LastIndex(header, "]") {
+		var err error
+		if host, _, err = net.SplitHostPort(header); err != nil {
+			return "", false
+		}
+	} else if strings.HasPrefix(header, "[") {
+		if !strings.HasSuffix(header, "]") {
+			return "", false
+		}
+		host = header[1 : len(header)-1]
+	}
+	if host == "" {
+		return "", false
+	}
+
+	return canonicalHost(host), true
+}
+
+func canonicalHost(host string) string {
+	return strings.TrimSuffix(strings.ToLower(host), ".")
+}
@@ -1,9 +1,15 @@
 package http
 
 import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
-	"strconv"
+	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/gorilla/mux"
@@ -17,128 +23,548 @@ import (
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
+const (
+	stateCreated int32 = iota
+	stateRunning
+	stateClosed
+)
+
+var (
+	ErrAlreadyOpen = errors.New("http adapter already open")
+	ErrClosed      = errors.New("http adapter closed")
+)
+
 type (
 	Adapter struct {
 		srv       *http.Server
-		port      int
-		host      string
-		version   string
+		router    *mux.Router
+		config    Config
 		healthy   bool
 		logger    kurin.Logger
 		lastError error
-		onStop    chan os.Signal
+		stopping  bool
+		notReady  bool
+		mu        sync.RWMutex
+		stop      kurin.StopNotifier
+		shutdown  chan struct{}
+		closeOnce sync.Once
+		listener  net.Listener
+		started   chan struct{}
+		startTime time.Time
+		clock     kurin.Clock
+		handler   atomic.Value
+		state     int32
+		conns     connTracker
+
+		middlewares             []Middleware
+		adminMiddlewares        []Middleware
+		metricsRecorder         MetricsRecorder
+		registry                *prometheus.Registry
+		registered              *trackingRegisterer
+		metricsHandler          http.Handler
+		metricsHandlerOpts      promhttp.HandlerOpts
+		summaryObjectives       map[float64]float64
+		traceID                 func(ctx context.Context) string
+		debugVars               bool
+		failures                *failureHistory
+		tcpKeepAlive            *time.Duration
+		tlsConfig               *tls.Config
+		maxHandshakes           int
+		clientCAs               *x509.CertPool
+		clientAuth              tls.ClientAuthType
+		healthRegistry          *kurin.HealthRegistry
+		ownHealthChecks         []kurin.HealthCheck
+		ignoreAppHealthChecks   bool
+		configErr               error
+		healthTimeout           time.Duration
+		healthFailFast          bool
+		requestIDGenerator      func() string
+		warming                 int32
+		uninstrumented          map[string]bool
+		slos                    map[string]time.Duration
+		contentTypes            map[string]bool
+		slowRequestThreshold    time.Duration
+		slowRequestLevel        kurin.Level
+		customLabels            []*customLabel
+		internalHandlers        map[string]http.Handler
+		maxURLLength            int
+		globalDeadline          time.Duration
+		tasks                   *kurin.TaskTracker
+		dependencies            map[string]bool
+		serverHeader            *string
+		degradedGauge           prometheus.Gauge
+		startTimeGauge          prometheus.Gauge
+		notFoundHandler         http.Handler
+		methodNotAllowedHandler http.Handler
+		recoveryHandler         http.Handler
+		handlerLabel            HandlerLabelStrategy
+		serializers             map[string]Serializer
+		rateLimitKey            func(*http.Request) string
+		idempotencyScope        func(*http.Request) string
+	}
+
+	Option func(*Adapter)
+
+	Middleware func(http.Handler) http.Handler
+
+	handlerHolder struct {
+		http.Handler
 	}
 )
 
-func NewHTTPAdapter(router *mux.Router, handler http.Handler, host string, port int, version string, logger kurin.Logger) kurin.Adapter {
+func NewHTTPAdapter(router *mux.Router, handler http.Handler, host string, port int, version string, logger kurin.Logger, options ...Option) kurin.Adapter {
+	config := DefaultConfig()
+	config.Host = host
+	config.Port = port
+	config.Version = version
+
+	return NewHTTPAdapterFromConfig(router, handler, config, logger, options...)
+}
+
+// NewHTTPAdapterFromConfig creates the adapter from a declarative configuration. Options are applied
+// on top of it, for instance to set the settings that cannot be serialized like handlers. A nil
+// logger defaults to kurin.NewDefaultLogger.
+func NewHTTPAdapterFromConfig(router *mux.Router, handler http.Handler, config Config, logger kurin.Logger, options ...Option) kurin.Adapter {
+	if logger == nil {
+		logger = kurin.NewDefaultLogger()
+	}
+
 	adapter := &Adapter{
-		port:    port,
-		host:    host,
-		version: version,
-		healthy: true,
-		logger:  logger,
+		router:   router,
+		config:   config,
+		healthy:  true,
+		logger:   logger,
+		shutdown: make(chan struct{}),
+		started:  make(chan struct{}),
+
+		requestIDGenerator: NewUUID,
+		clock:              kurin.SystemClock,
+		handlerLabel:       RouteTemplateOrOther,
+		rateLimitKey:       clientIP,
+		idempotencyScope:   credentials,
+		slowRequestLevel:   kurin.LevelWarn,
+		healthRegistry:     kurin.NewHealthRegistry(),
 	}
 
-	totalCount := prometheus.NewCounterVec(
-		prometheus.CounterOpts{
-			Name: "app_requests_total",
-			Help: "A counter for requests to the wrapped handler.",
-		},
-		[]string{"code", "method", "handler"},
-	)
-	durationHist := prometheus.NewHistogramVec(
-		prometheus.HistogramOpts{
-			Name:    "app_response_duration_seconds",
-			Help:    "A histogram of request latencies.",
-			Buckets: prometheus.DefBuckets,
-		},
-		[]string{"code", "method", "handler"},
-	)
-	prometheus.MustRegister(totalCount, durationHist)
+	for _, option := range options {
+		option(adapter)
+	}
+	if adapter.config.ClientClosedCode == 0 {
+		// A configuration not built from DefaultConfig would label them with code 0.
+		adapter.config.ClientClosedCode = defaultClientClosedCode
+	}
+	adapter.middlewares = append(adapter.config.Middlewares.middlewares(adapter), adapter.middlewares...)
+	if len(adapter.serializers) > 0 {
+		adapter.middlewares = append([]Middleware{adapter.serializersMiddleware}, adapter.middlewares...)
+	}
+	if adapter.config.Name != "" {
+		adapter.logger = kurin.WithFields(adapter.logger, "adapter", adapter.config.Name)
+	}
+	adapter.tasks = kurin.NewTaskTracker(adapter.logger)
+
+	if adapter.notFoundHandler != nil {
+		router.NotFoundHandler = adapter.notFoundHandler
+	} else if router.NotFoundHandler == nil {
+		router.NotFoundHandler = errorHandler(http.StatusNotFound, "not_found")
+	}
+	if adapter.methodNotAllowedHandler != nil {
+		router.MethodNotAllowedHandler = adapter.methodNotAllowedHandler
+	} else if router.MethodNotAllowedHandler == nil {
+		router.MethodNotAllowedHandler = errorHandler(http.StatusMethodNotAllowed, "method_not_allowed")
+	}
 
 	mux := http.NewServeMux()
-	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
-		if adapter.healthy {
-			w.WriteHeader(http.StatusNoContent)
-		} else {
-			w.WriteHeader(http.StatusServiceUnavailable)
-			w.Write([]byte(adapter.lastError.Error()))
-		}
-	})
-	mux.HandleFunc("/version", func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusOK)
-		fmt.Fprintf(w, version)
-	})
-	mux.Handle("/metrics", promhttp.Handler())
-	mux.Handle("/", handlerCounter(router, totalCount, handlerDuration(router, durationHist, handler)))
+	if adapter.config.HealthPath != "" {
+		mux.HandleFunc(adapter.config.HealthPath, adapter.healthHandler)
+	}
+	if adapter.config.LivePath != "" {
+		mux.HandleFunc(adapter.config.LivePath, adapter.liveHandler)
+	}
+	if adapter.config.ReadyPath != "" {
+		mux.HandleFunc(adapter.config.ReadyPath, adapter.readyHandler)
+	}
+	if adapter.config.VersionPath != "" {
+		mux.HandleFunc(adapter.config.VersionPath, adapter.versionHandler)
+	}
+	if adapter.config.AdminPath != "" {
+		adapter.mountAdmin(mux)
+	}
+	for path, h := range adapter.internalHandlers {
+		mux.Handle(path, h)
+	}
 
-	fmt.Println("address is")
-	fmt.Println(fmt.Sprintf("%s:%d", host, port))
+	if adapter.recoveryHandler == nil {
+		adapter.recoveryHandler = errorHandler(http.StatusInternalServerError, "internal_error")
+	}
+
+	adapter.ReplaceHandler(handler)
+	handler = http.HandlerFunc(adapter.serveHandler)
+	for i := len(adapter.middlewares) - 1; i >= 0; i-- {
+		handler = adapter.middlewares[i](handler)
+	}
+	handler = adapter.recover(handler)
+	if !adapter.config.DisableMetrics {
+		if adapter.metricsRecorder == nil {
+			var registerer prometheus.Registerer = prometheus.DefaultRegisterer
+			var gatherer prometheus.Gatherer = prometheus.DefaultGatherer
+			if adapter.registry != nil {
+				registerer, gatherer = adapter.registry, adapter.registry
+			}
+			// Exemplars are only exposed in the OpenMetrics format.
+			opts := adapter.metricsHandlerOpts
+			opts.EnableOpenMetrics = opts.EnableOpenMetrics || adapter.traceID != nil
+			metricsHandler := promhttp.HandlerFor(gatherer, opts)
+			if adapter.registry == nil {
+				metricsHandler = promhttp.InstrumentMetricHandler(registerer, metricsHandler)
+			}
+			if adapter.config.Name != "" {
+				registerer = prometheus.WrapRegistererWith(prometheus.Labels{"adapter": adapter.config.Name}, registerer)
+			}
+			adapter.registered = &trackingRegisterer{Registerer: registerer}
+			registerer = adapter.registered
+
+			adapter.metricsRecorder = newPrometheusRecorder(registerer, adapter.summaryObjectives, adapter.customLabelNames())
+			adapter.startTimeGauge = prometheus.NewGauge(prometheus.GaugeOpts{
+				Name: "app_start_time_seconds",
+				Help: "Start time of the HTTP adapter since unix epoch in seconds.",
+			})
+			registerer.MustRegister(adapter.startTimeGauge)
+			// The application may give checks of its own once the adapter is created.
+			adapter.degradedGauge = prometheus.NewGauge(prometheus.GaugeOpts{
+				Name: "app_health_degraded",
+				Help: "Whether a non critical health check failed on the last health probe (1) or not (0).",
+			})
+			registerer.MustRegister(adapter.degradedGauge)
+			if adapter.debugVars {
+				info := ReadBuildInfo(adapter.config.Version)
+				buildInfo := prometheus.NewGauge(prometheus.GaugeOpts{
+					Name:        "app_build_info",
+					Help:        "Build information of the application, always 1.",
+					ConstLabels: prometheus.Labels{"version": info.Version, "commit": info.Commit, "goversion": info.GoVersion},
+				})
+				buildInfo.Set(1)
+				registerer.MustRegister(buildInfo)
+			}
+			if adapter.metricsHandler != nil {
+				metricsHandler = adapter.metricsHandler
+			}
+			if adapter.config.MetricsPath != "" {
+				mux.Handle(adapter.config.MetricsPath, metricsHandler)
+			}
+		}
+		handler = adapter.instrument(handler)
+	}
+	if adapter.maxURLLength > 0 {
+		handler = maxURLLengthMiddleware(adapter.maxURLLength)(handler)
+	}
+	if adapter.globalDeadline > 0 {
+		handler = deadlineMiddleware(adapter.globalDeadline)(handler)
+	}
+	mux.Handle("/", handler)
+	root := adapter.conns.middleware(mux)
+	if adapter.serverHeader != nil {
+		root = serverHeaderMiddleware(*adapter.serverHeader)(root)
+	}
 
 	adapter.srv = &http.Server{
-		Addr:         fmt.Sprintf("%s:%d", host, port),
-		Handler:      mux,
-		ReadTimeout:  10 * time.Second,
-		WriteTimeout: 10 * time.Second,
+		Addr:              fmt.Sprintf("%s:%d", adapter.config.Host, adapter.config.Port),
+		Handler:           root,
+		ReadTimeout:       time.Duration(adapter.config.ReadTimeout),
+		ReadHeaderTimeout: time.Duration(adapter.config.ReadHeaderTimeout),
+		WriteTimeout:      time.Duration(adapter.config.WriteTimeout),
+		ConnState:         adapter.conns.track,
+		BaseContext: func(net.Listener) context.Context {
+			ctx := context.WithValue(context.Background(), streamingKey{}, adapter.shutdown)
+			return kurin.ContextWithTaskTracker(ctx, adapter.tasks)
+		},
+	}
+	if adapter.tlsConfig != nil && adapter.clientCAs != nil {
+		adapter.tlsConfig.ClientCAs = adapter.clientCAs
+		adapter.tlsConfig.ClientAuth = adapter.clientAuth
 	}
+	if adapter.tlsConfig != nil && adapter.maxHandshakes > 0 && len(adapter.tlsConfig.NextProtos) == 0 {
+		// Unlike ServeTLS, Serve only sets HTTP/2 up when the configuration advertises it.
+		adapter.tlsConfig.NextProtos = []string{"h2", "http/1.1"}
+	}
+	adapter.srv.TLSConfig = adapter.tlsConfig
+	adapter.srv.RegisterOnShutdown(func() {
+		adapter.closeOnce.Do(func() {
+			close(adapter.shutdown)
+		})
+	})
 
 	return adapter
 }
 
-func handlerCounter(router *mux.Router, totalCount *prometheus.CounterVec, next http.Handler) http.HandlerFunc {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		crw := NewCustomResponseWriter(w)
-		next.ServeHTTP(crw, r)
-		totalCount.With(createLabelsFromRequestResponse(router, r, crw)).Inc()
-	})
-}
+// Handle registers h on the router for the path template, behind the given middlewares which, unlike
+// the ones set up by the options, only run for this route. The first middleware is the outermost.
+func (adapter *Adapter) Handle(template string, h http.Handler, middlewares ...Middleware) *mux.Route {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		h = middlewares[i](h)
+	}
 
-func handlerDuration(router *mux.Router, durationHist *prometheus.HistogramVec, next http.Handler) http.HandlerFunc {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		crw := NewCustomResponseWriter(w)
-		now := time.Now()
-		next.ServeHTTP(crw, r)
-		durationHist.With(createLabelsFromRequestResponse(router, r, crw)).Observe(time.Since(now).Seconds())
-	})
+	return adapter.router.Handle(template, h)
 }
 
-func createLabelsFromRequestResponse(router *mux.Router, r *http.Request, crw *customResponseWriter) prometheus.Labels {
-	handler := r.URL.Path
-	var match mux.RouteMatch
-	routeExists := router.Match(r, &match)
-	if routeExists && match.Route != nil {
-		handler, _ = match.Route.GetPathTemplate()
+// Registry returns the registry the adapter metrics are registered on, the one of WithRegistry or
+// the default one, e.g. to expose them with another handler.
+func (adapter *Adapter) Registry() *prometheus.Registry {
+	if adapter.registry != nil {
+		return adapter.registry
 	}
+	registry, _ := prometheus.DefaultRegisterer.(*prometheus.Registry)
+
+	return registry
+}
 
-	labels := prometheus.Labels{}
-	labels["method"] = r.Method
-	labels["handler"] = handler
-	labels["code"] = strconv.Itoa(crw.statusCode)
+// ReplaceHandler swaps the application handler without restarting the server: requests in flight
+// finish on the previous handler while new ones are served by h. Only the handler given to the
+// constructor is replaced, the middlewares and instrumentation set up by the options stay. A nil
+// handler serves the router.
+func (adapter *Adapter) ReplaceHandler(h http.Handler) {
+	if h == nil {
+		h = adapter.router
+	}
+	adapter.handler.Store(handlerHolder{h})
+}
 
-	return labels
+func (adapter *Adapter) serveHandler(w http.ResponseWriter, r *http.Request) {
+	adapter.handler.Load().(handlerHolder).ServeHTTP(w, r)
 }
 
+// Open serves like OpenContext, an error being fatal unless the adapter is already open or closed.
 func (adapter *Adapter) Open() {
-	adapter.logger.Info(fmt.Sprintf("host issss %s", adapter.host))
-	adapter.logger.Info(fmt.Sprintf("Listening on http://%s:%d", adapter.host, adapter.port))
-	if err := adapter.srv.ListenAndServe(); err != nil {
+	if err := adapter.OpenContext(context.Background()); err != nil {
+		if errors.Is(err, ErrAlreadyOpen) || errors.Is(err, ErrClosed) {
+			adapter.logger.Error(err)
+			return
+		}
 		adapter.logger.Fatal(err)
 	}
 }
 
+// OpenContext listens and serves until the server is shut down or ctx is cancelled, leaving
+// the shutdown to Close in that case. It returns ErrAlreadyOpen if the adapter is already
+// open and ErrClosed once it is closed, and can be called again when it failed to listen.
+func (adapter *Adapter) OpenContext(ctx context.Context) error {
+	if !atomic.CompareAndSwapInt32(&adapter.state, stateCreated, stateRunning) {
+		if atomic.LoadInt32(&adapter.state) == stateClosed {
+			return ErrClosed
+		}
+		return ErrAlreadyOpen
+	}
+
+	if adapter.clientCAs != nil && adapter.tlsConfig == nil {
+		atomic.CompareAndSwapInt32(&adapter.state, stateRunning, stateCreated)
+		return fmt.Errorf("%w: mutual TLS requires a TLS configuration", kurin.ErrConfig)
+	}
+	if adapter.configErr != nil {
+		atomic.CompareAndSwapInt32(&adapter.state, stateRunning, stateCreated)
+		return adapter.configErr
+	}
+
+	listener := adapter.listener
+	if listener == nil {
+		var err error
+		if listener, err = net.Listen("tcp", adapter.srv.Addr); err != nil {
+			atomic.CompareAndSwapInt32(&adapter.state, stateRunning, stateCreated)
+			return fmt.Errorf("%w: %w", kurin.ErrBind, err)
+		}
+	} else {
+		listener = newCallerListener(listener)
+	}
+	if adapter.tcpKeepAlive != nil {
+		listener = &keepAliveListener{Listener: listener, period: *adapter.tcpKeepAlive}
+	}
+
+	adapter.mu.Lock()
+	adapter.listener = listener
+	adapter.startTime = adapter.clock.Now()
+	adapter.mu.Unlock()
+	if adapter.startTimeGauge != nil {
+		adapter.startTimeGauge.Set(float64(adapter.startTime.UnixNano()) / 1e9)
+	}
+	close(adapter.started)
+
+	scheme := adapter.scheme()
+	adapter.logger.Info(fmt.Sprintf("Listening on %s://%s", scheme, listener.Addr()))
+	served := make(chan error, 1)
+	go func() {
+		switch {
+		case adapter.tlsConfig != nil && adapter.maxHandshakes > 0:
+			handshakes := newHandshakeListener(listener, adapter.tlsConfig, adapter.maxHandshakes, adapter.handshakeTimeout())
+			served <- adapter.srv.Serve(handshakes)
+		case adapter.tlsConfig != nil:
+			served <- adapter.srv.ServeTLS(listener, "", "")
+		default:
+			served <- adapter.srv.Serve(listener)
+		}
+	}()
+
+	select {
+	case err := <-served:
+		if err == http.ErrServerClosed {
+			return nil
+		}
+		return err
+	case <-ctx.Done():
+		return nil
+	}
+}
+
+// Started is closed once the adapter is listening, from then Port returns the bound port.
+func (adapter *Adapter) Started() <-chan struct{} {
+	return adapter.started
+}
+
+// Port returns the port the adapter listens on, which is only known after it started when
+// configured with port 0 to get one assigned by the system.
+func (adapter *Adapter) Port() int {
+	adapter.mu.RLock()
+	defer adapter.mu.RUnlock()
+
+	if adapter.listener != nil {
+		if addr, ok := adapter.listener.Addr().(*net.TCPAddr); ok {
+			return addr.Port
+		}
+	}
+
+	return adapter.config.Port
+}
+
+// Descriptor reports the address and port the adapter listens on, once started, along its health
+// endpoint path.
+func (adapter *Adapter) Descriptor() kurin.Descriptor {
+	descriptor := kurin.Descriptor{
+		Protocol:   adapter.scheme(),
+		Address:    adapter.config.Host,
+		Port:       adapter.config.Port,
+		HealthPath: adapter.config.HealthPath,
+	}
+
+	adapter.mu.RLock()
+	defer adapter.mu.RUnlock()
+	if adapter.listener != nil {
+		if addr, ok := adapter.listener.Addr().(*net.TCPAddr); ok {
+			descriptor.Address = addr.IP.String()
+			descriptor.Port = addr.Port
+		}
+	}
+
+	return descriptor
+}
+
+func (adapter *Adapter) scheme() string {
+	if adapter.tlsConfig != nil {
+		return "https"
+	}
+
+	return "http"
+}
+
+// Close shuts the server down like CloseContext, within the shutdown timeout if any.
 func (adapter *Adapter) Close() {
-	if err := adapter.srv.Shutdown(context.Background()); err != nil {
+	ctx := context.Background()
+	if timeout := adapter.ShutdownTimeout(); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	if err := adapter.CloseContext(ctx); err != nil {
 		adapter.logger.Error(err)
 	}
 }
 
+// CloseContext shuts the server down gracefully, logging its progress, until ctx is done or the
+// shutdown fails when the remaining connections are forced closed. From its start, pre-stop delay
+// included, the responses close their connection so that the clients stop reusing it. It then
+// waits for the goroutines started by the handlers with kurin.Go until ctx is done, and unregisters
+// the metrics of the adapter so that another one can be created on the same registry.
+func (adapter *Adapter) CloseContext(ctx context.Context) error {
+	atomic.StoreInt32(&adapter.state, stateClosed)
+	// The responses, those in flight included, close their connection from now on.
+	adapter.srv.SetKeepAlivesEnabled(false)
+	adapter.stop.Stop(syscall.SIGTERM)
+	// The readiness probe fails from now on, through the drain too.
+	adapter.mu.Lock()
+	adapter.stopping = true
+	adapter.mu.Unlock()
+	if delay := time.Duration(adapter.config.PreStopDelay); delay > 0 {
+		adapter.logger.Info(fmt.Sprintf("Waiting %s before shutting down the http server...", delay))
+		select {
+		case <-adapter.clock.After(delay):
+		case <-ctx.Done():
+		}
+	}
+
+	err := adapter.drain(ctx)
+	if tasksErr := adapter.tasks.Drain(ctx); err == nil {
+		err = tasksErr
+	}
+	if adapter.registered != nil {
+		adapter.registered.unregisterAll()
+	}
+
+	return err
+}
+
+// Tasks returns the tracker of the goroutines started with kurin.Go by the handlers, waited for by
+// CloseContext.
+func (adapter *Adapter) Tasks() *kurin.TaskTracker {
+	return adapter.tasks
+}
+
+// Name is the name given to the adapter, if any.
+func (adapter *Adapter) Name() string {
+	return adapter.config.Name
+}
+
+// ShutdownTimeout is the deadline the application gives to CloseContext, none when 0.
+func (adapter *Adapter) ShutdownTimeout() time.Duration {
+	return time.Duration(adapter.config.ShutdownTimeout)
+}
+
+// NotifyStop subscribes c to the adapter stop, notified with SIGTERM when it starts closing.
 func (adapter *Adapter) NotifyStop(c chan os.Signal) {
-	adapter.onStop = c
+	adapter.stop.NotifyStop(c)
 }
 
+// OnFailure reports the adapter as unhealthy with the error, or healthy again once every system
+// recovered (nil error), on the health and readiness endpoints. The liveness one is untouched, a
+// failing dependency not being a reason to restart the process.
 func (adapter *Adapter) OnFailure(err error) {
-	if err != nil {
-		adapter.lastError = err
-		adapter.healthy = false
+	if err == nil {
+		adapter.MarkHealthy()
+		return
+	}
+	adapter.MarkUnhealthy(err)
+}
+
+// IsHealthy reports whether the health endpoint answers the adapter is healthy, regardless of
+// it shutting down.
+func (adapter *Adapter) IsHealthy() bool {
+	adapter.mu.RLock()
+	defer adapter.mu.RUnlock()
+
+	return adapter.healthy
+}
+
+// MarkUnhealthy reports the adapter as unhealthy with err, as when a system fails.
+func (adapter *Adapter) MarkUnhealthy(err error) {
+	adapter.mu.Lock()
+	adapter.lastError = err
+	adapter.healthy = false
+	adapter.mu.Unlock()
+
+	if adapter.failures != nil && err != nil {
+		adapter.failures.add(adapter.clock.Now(), err)
 	}
 }
+
+// MarkHealthy reports the adapter as healthy again, as when a system recovered.
+func (adapter *Adapter) MarkHealthy() {
+	adapter.mu.Lock()
+	adapter.lastError = nil
+	adapter.healthy = true
+	adapter.mu.Unlock()
+}
@@ -0,0 +1,172 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/maxperrimond/kurin"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func newTestAdapter(t *testing.T, router *mux.Router, options ...Option) *Adapter {
+	t.Helper()
+
+	if router == nil {
+		router = mux.NewRouter()
+	}
+	options = append([]Option{WithRegistry(prometheus.NewRegistry())}, options...)
+
+	return NewHTTPAdapter(router, nil, "127.0.0.1", 0, "test", kurin.NewStdLogger(io.Discard, kurin.LevelError), options...).(*Adapter)
+}
+
+// startTestAdapter opens the adapter on a free port, closing it at the end of the test.
+func startTestAdapter(t *testing.T, adapter *Adapter) {
+	t.Helper()
+
+	served := make(chan error, 1)
+	go func() {
+		served <- adapter.OpenContext(context.Background())
+	}()
+	select {
+	case <-adapter.Started():
+	case err := <-served:
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		adapter.CloseContext(context.Background())
+	})
+}
+
+func TestOpenOnFreePorts(t *testing.T) {
+	first, second := newTestAdapter(t, nil), newTestAdapter(t, nil)
+	startTestAdapter(t, first)
+	startTestAdapter(t, second)
+
+	if first.Port() == 0 || first.Port() == second.Port() {
+		t.Fatalf("got ports %d and %d, want distinct bound ports", first.Port(), second.Port())
+	}
+	for _, adapter := range []*Adapter{first, second} {
+		resp, err := http.Get(fmt.Sprintf("http://127.0.0.1:%d/health", adapter.Port()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusNoContent {
+			t.Errorf("port %d: got status %d, want 204", adapter.Port(), resp.StatusCode)
+		}
+	}
+}
+
+func TestHandleMiddlewares(t *testing.T) {
+	auth := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "Bearer admin" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	adapter := newTestAdapter(t, nil)
+	adapter.Handle("/admin", ok, auth)
+	adapter.Handle("/public", ok)
+
+	for _, test := range []struct {
+		path, authorization string
+		code                int
+	}{
+		{"/admin", "", http.StatusUnauthorized},
+		{"/admin", "Bearer admin", http.StatusOK},
+		{"/public", "", http.StatusOK},
+	} {
+		r := httptest.NewRequest(http.MethodGet, test.path, nil)
+		if test.authorization != "" {
+			r.Header.Set("Authorization", test.authorization)
+		}
+		w := httptest.NewRecorder()
+		adapter.srv.Handler.ServeHTTP(w, r)
+
+		if w.Code != test.code {
+			t.Errorf("%s with authorization %q: got status %d, want %d", test.path, test.authorization, w.Code, test.code)
+		}
+	}
+}
+
+func TestListenerOwnedByCaller(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	adapter := newTestAdapter(t, nil, WithListener(listener))
+
+	served := make(chan error, 1)
+	go func() {
+		served <- adapter.OpenContext(context.Background())
+	}()
+	<-adapter.Started()
+	resp, err := http.Get(fmt.Sprintf("http://%s/health", listener.Addr()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if err := adapter.CloseContext(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-served:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the adapter still serves once closed")
+	}
+
+	accepted := make(chan error, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err == nil {
+			conn.Close()
+		}
+		accepted <- err
+	}()
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+	if err := <-accepted; err != nil {
+		t.Errorf("the listener was closed: %v", err)
+	}
+}
+
+func TestOpenTwice(t *testing.T) {
+	adapter := newTestAdapter(t, nil)
+	startTestAdapter(t, adapter)
+
+	if err := adapter.OpenContext(context.Background()); !errors.Is(err, ErrAlreadyOpen) {
+		t.Errorf("got %v, want ErrAlreadyOpen", err)
+	}
+}
+
+func TestOpenAfterClose(t *testing.T) {
+	adapter := newTestAdapter(t, nil)
+	startTestAdapter(t, adapter)
+	if err := adapter.CloseContext(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := adapter.OpenContext(context.Background()); !errors.Is(err, ErrClosed) {
+		t.Errorf("got %v, want ErrClosed", err)
+	}
+}
@@ -0,0 +1,230 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/maxperrimond/kurin"
+)
+
+const (
+	idempotencyKeyHeader      = "Idempotency-Key"
+	idempotencyReplayedHeader = "Idempotent-Replayed"
+)
+
+type (
+	// IdempotencyStore keeps the responses of the requests with an idempotency key, see WithIdempotency.
+	// Get reports whether a response is stored for the key.
+	IdempotencyStore interface {
+		Get(ctx context.Context, key string) (*StoredResponse, bool, error)
+		Set(ctx context.Context, key string, response *StoredResponse, ttl time.Duration) error
+	}
+
+	// StoredResponse is the response to an idempotent request, along the fingerprint of the
+	// request, its method, path and body, told apart from another request reusing the key.
+	StoredResponse struct {
+		Status      int
+		Header      http.Header
+		Trailer     http.Header
+		Body        []byte
+		Fingerprint string
+	}
+
+	memoryIdempotencyStore struct {
+		mu        sync.Mutex
+		responses map[string]*storedEntry
+		clock     kurin.Clock
+	}
+
+	storedEntry struct {
+		response *StoredResponse
+		expires  time.Time
+	}
+
+	// recordingWriter writes the response through while keeping a copy of it, up to maxBytes.
+	recordingWriter struct {
+		http.ResponseWriter
+		response    StoredResponse
+		wroteHeader bool
+		maxBytes    int
+		tooLarge    bool
+	}
+)
+
+// NewMemoryIdempotencyStore returns a store keeping the responses in memory, for a single instance.
+func NewMemoryIdempotencyStore() IdempotencyStore {
+	return NewMemoryIdempotencyStoreWithClock(kurin.SystemClock)
+}
+
+// NewMemoryIdempotencyStoreWithClock returns a memory store expiring the responses with the clock.
+func NewMemoryIdempotencyStoreWithClock(clock kurin.Clock) IdempotencyStore {
+	return &memoryIdempotencyStore{responses: make(map[string]*storedEntry), clock: clock}
+}
+
+func (store *memoryIdempotencyStore) Get(ctx context.Context, key string) (*StoredResponse, bool, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	entry, ok := store.responses[key]
+	if !ok {
+		return nil, false, nil
+	}
+	if store.clock.Now().After(entry.expires) {
+		delete(store.responses, key)
+		return nil, false, nil
+	}
+
+	return entry.response, true, nil
+}
+
+func (store *memoryIdempotencyStore) Set(ctx context.Context, key string, response *StoredResponse, ttl time.Duration) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	now := store.clock.Now()
+	for k, entry := range store.responses {
+		if now.After(entry.expires) {
+			delete(store.responses, k)
+		}
+	}
+	store.responses[key] = &storedEntry{response: response, expires: now.Add(ttl)}
+
+	return nil
+}
+
+// credentials is the default scope of the idempotency keys, a hash of the credentials of the
+// request, its Authorization and Cookie headers, or its client IP without any.
+func credentials(r *http.Request) string {
+	authorization, cookie := r.Header.Get("Authorization"), r.Header.Get("Cookie")
+	if authorization == "" && cookie == "" {
+		return clientIP(r)
+	}
+	sum := sha256.Sum256([]byte(authorization + "\x00" + cookie))
+
+	return hex.EncodeToString(sum[:])
+}
+
+func (adapter *Adapter) idempotencyMiddleware(store IdempotencyStore, ttl time.Duration, maxBytes int) Middleware {
+	var mu sync.Mutex
+	inFlight := make(map[string]bool)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(idempotencyKeyHeader)
+			if r.Method != http.MethodPost || key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			key = adapter.idempotencyScope(r) + "\x00" + r.URL.Path + "\x00" + key
+
+			body, err := io.ReadAll(io.LimitReader(r.Body, int64(maxBytes)+1))
+			if err != nil {
+				writeError(w, http.StatusBadRequest, "invalid_body")
+				return
+			}
+			if len(body) > maxBytes {
+				writeError(w, http.StatusRequestEntityTooLarge, "idempotency_body_too_large")
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			sum := sha256.Sum256(append([]byte(r.Method+" "+r.URL.Path+"\x00"), body...))
+			fingerprint := hex.EncodeToString(sum[:])
+
+			mu.Lock()
+			if inFlight[key] {
+				mu.Unlock()
+				writeError(w, http.StatusConflict, "idempotency_key_in_use")
+				return
+			}
+			inFlight[key] = true
+			mu.Unlock()
+			defer func() {
+				mu.Lock()
+				delete(inFlight, key)
+				mu.Unlock()
+			}()
+
+			stored, ok, err := store.Get(r.Context(), key)
+			if err != nil {
+				adapter.logger.Error(fmt.Sprintf("Unable to get the idempotent response: %s", err))
+				writeError(w, http.StatusServiceUnavailable, "idempotency_unavailable")
+				return
+			}
+			if ok && stored.Fingerprint != fingerprint {
+				writeError(w, http.StatusUnprocessableEntity, "idempotency_key_reused")
+				return
+			}
+			if ok {
+				for k, values := range stored.Header {
+					w.Header()[k] = append([]string(nil), values...)
+				}
+				w.Header().Set(idempotencyReplayedHeader, "true")
+				w.WriteHeader(stored.Status)
+				w.Write(stored.Body)
+				writeTrailers(w, stored.Trailer)
+				return
+			}
+
+			rw := &recordingWriter{ResponseWriter: w, response: StoredResponse{Status: http.StatusOK, Fingerprint: fingerprint}, maxBytes: maxBytes}
+			next.ServeHTTP(rw, r)
+			rw.snapshotHeader()
+			rw.response.Trailer = trailers(rw.Header())
+			for key := range rw.response.Trailer {
+				delete(rw.response.Header, key)
+			}
+			// Server errors are not stored so that the client can retry them.
+			if rw.response.Status >= http.StatusInternalServerError {
+				return
+			}
+			if rw.tooLarge {
+				adapter.logger.Warn(fmt.Sprintf("Idempotent response to %s larger than %d bytes, not stored", r.URL.Path, maxBytes))
+				return
+			}
+			if err := store.Set(r.Context(), key, &rw.response, ttl); err != nil {
+				adapter.logger.Error(fmt.Sprintf("Unable to store the idempotent response: %s", err))
+			}
+		})
+	}
+}
+
+func (rw *recordingWriter) WriteHeader(code int) {
+	if !rw.wroteHeader {
+		rw.response.Status = code
+		rw.snapshotHeader()
+	}
+	rw.ResponseWriter.WriteHeader(code)
+}
+
+func (rw *recordingWriter) Write(b []byte) (int, error) {
+	rw.snapshotHeader()
+	n, err := rw.ResponseWriter.Write(b)
+	if len(rw.response.Body)+n > rw.maxBytes {
+		rw.tooLarge = true
+		rw.response.Body = nil
+	}
+	if !rw.tooLarge {
+		rw.response.Body = append(rw.response.Body, b[:n]...)
+	}
+
+	return n, err
+}
+
+// Unwrap gives http.ResponseController access to the wrapped writer.
+func (rw *recordingWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+func (rw *recordingWriter) snapshotHeader() {
+	if rw.wroteHeader {
+		return
+	}
+	rw.wroteHeader = true
+	rw.response.Header = rw.Header().Clone()
+}
@@ -0,0 +1,72 @@
+package http
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+func TestIdempotency(t *testing.T) {
+	calls := 0
+	router := mux.NewRouter()
+	router.HandleFunc("/orders", func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		body, _ := io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(r.Header.Get("Authorization") + " " + string(body)))
+	})
+	adapter := newTestAdapter(t, router, WithIdempotency(NewMemoryIdempotencyStore(), time.Minute, 1024))
+	post := func(authorization, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(body))
+		r.Header.Set("Authorization", authorization)
+		r.Header.Set(idempotencyKeyHeader, "order-1")
+		w := httptest.NewRecorder()
+		adapter.srv.Handler.ServeHTTP(w, r)
+		return w
+	}
+
+	post("alice", "one book")
+	if w := post("alice", "one book"); w.Code != http.StatusCreated || w.Header().Get(idempotencyReplayedHeader) != "true" || w.Body.String() != "alice one book" {
+		t.Errorf("got %d %q replayed %q, want the stored response", w.Code, w.Body.String(), w.Header().Get(idempotencyReplayedHeader))
+	}
+	if w := post("bob", "one book"); w.Header().Get(idempotencyReplayedHeader) != "" || w.Body.String() != "bob one book" {
+		t.Errorf("got %q, want the key of another client to run the handler", w.Body.String())
+	}
+	if w := post("alice", "two books"); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("got %d, want 422 for a key reused with another body", w.Code)
+	}
+	if calls != 2 {
+		t.Errorf("got %d calls, want 2", calls)
+	}
+	if w := post("carol", strings.Repeat("a", 2048)); w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("got %d, want 413 for a body over the limit", w.Code)
+	}
+}
+
+func TestIdempotencyLargeResponses(t *testing.T) {
+	calls := 0
+	router := mux.NewRouter()
+	router.HandleFunc("/exports", func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Write([]byte(strings.Repeat("a", 2048)))
+	})
+	adapter := newTestAdapter(t, router, WithIdempotency(NewMemoryIdempotencyStore(), time.Minute, 1024))
+
+	for i := 0; i < 2; i++ {
+		r := httptest.NewRequest(http.MethodPost, "/exports", nil)
+		r.Header.Set(idempotencyKeyHeader, "export-1")
+		w := httptest.NewRecorder()
+		adapter.srv.Handler.ServeHTTP(w, r)
+		if w.Body.Len() != 2048 {
+			t.Errorf("got %d bytes, want the whole response", w.Body.Len())
+		}
+	}
+	if calls != 2 {
+		t.Errorf("got %d calls, want the response over the limit not stored", calls)
+	}
+}
@@ -0,0 +1,74 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/maxperrimond/kurin"
+)
+
+const (
+	maxCustomLabelValues = 100
+	otherLabelValue      = "other"
+)
+
+// customLabel computes the value of a label of WithCustomLabel, keeping track of the distinct values
+// to bound them.
+type customLabel struct {
+	name   string
+	fn     func(*http.Request) string
+	mu     sync.Mutex
+	values map[string]bool
+	warned bool
+}
+
+var (
+	labelNamePattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+	reservedLabels   = map[string]bool{"code": true, "method": true, "handler": true}
+)
+
+func (adapter *Adapter) addCustomLabel(name string, fn func(*http.Request) string) {
+	if !labelNamePattern.MatchString(name) || strings.HasPrefix(name, "__") || reservedLabels[name] {
+		panic(fmt.Sprintf("invalid custom label name %q", name))
+	}
+	for _, label := range adapter.customLabels {
+		if label.name == name {
+			panic(fmt.Sprintf("duplicate custom label %q", name))
+		}
+	}
+
+	adapter.customLabels = append(adapter.customLabels, &customLabel{name: name, fn: fn, values: make(map[string]bool)})
+}
+
+func (adapter *Adapter) customLabelNames() []string {
+	names := make([]string, 0, len(adapter.customLabels))
+	for _, label := range adapter.customLabels {
+		names = append(names, label.name)
+	}
+
+	return names
+}
+
+func (label *customLabel) value(r *http.Request, logger kurin.Logger) string {
+	value := label.fn(r)
+
+	label.mu.Lock()
+	defer label.mu.Unlock()
+
+	if label.values[value] {
+		return value
+	}
+	if len(label.values) < maxCustomLabelValues {
+		label.values[value] = true
+		return value
+	}
+	if !label.warned {
+		label.warned = true
+		logger.Warn(fmt.Sprintf("Custom label %s has more than %d distinct values, recording the new ones as %s", label.name, maxCustomLabelValues, otherLabelValue))
+	}
+
+	return otherLabelValue
+}
@@ -0,0 +1,75 @@
+package http
+
+import (
+	"net"
+	"sync"
+	"time"
+)
+
+type keepAliveListener struct {
+	net.Listener
+	period time.Duration
+}
+
+func (listener *keepAliveListener) Accept() (net.Conn, error) {
+	conn, err := listener.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+
+	if tcpConn, ok := conn.(*net.TCPConn); ok {
+		if listener.period > 0 {
+			tcpConn.SetKeepAlive(true)
+			tcpConn.SetKeepAlivePeriod(listener.period)
+		} else {
+			tcpConn.SetKeepAlive(false)
+		}
+	}
+
+	return conn, nil
+}
+
+// callerListener keeps the listener of WithListener open when the server closes it, the caller
+// owning it. Closing it stops its Accept instead, through a past deadline when the listener has
+// one, else on the next connection which is then closed.
+type callerListener struct {
+	net.Listener
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+type deadliner interface {
+	SetDeadline(t time.Time) error
+}
+
+func newCallerListener(l net.Listener) *callerListener {
+	return &callerListener{Listener: l, closed: make(chan struct{})}
+}
+
+func (listener *callerListener) Accept() (net.Conn, error) {
+	conn, err := listener.Listener.Accept()
+	select {
+	case <-listener.closed:
+		if conn != nil {
+			conn.Close()
+		}
+		if d, ok := listener.Listener.(deadliner); ok {
+			d.SetDeadline(time.Time{})
+		}
+		return nil, net.ErrClosed
+	default:
+	}
+
+	return conn, err
+}
+
+func (listener *callerListener) Close() error {
+	listener.closeOnce.Do(func() {
+		close(listener.closed)
+		if d, ok := listener.Listener.(deadliner); ok {
+			d.SetDeadline(time.Unix(1, 0))
+		}
+	})
+
+	return nil
+}
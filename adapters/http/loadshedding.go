@@ -0,0 +1,146 @@
+package http
+
+import (
+	"math/rand"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+type (
+	// LoadSheddingConfig configures WithLoadShedding. Zero values get the defaults of
+	// DefaultLoadSheddingConfig, except LatencyThreshold which is required.
+	LoadSheddingConfig struct {
+		// LatencyThreshold is the latency above which requests are shed.
+		LatencyThreshold time.Duration
+		// Quantile of the latencies compared to the threshold, 0.99 by default.
+		Quantile float64
+		// Interval between two evaluations of the quantile of the latencies observed since the
+		// previous one, up to Samples, each raising or lowering the fraction of shed requests
+		// by Step, up to MaxFraction.
+		Interval    time.Duration
+		Samples     int
+		Step        float64
+		MaxFraction float64
+		// Critical requests are never shed.
+		Critical func(r *http.Request) bool
+	}
+
+	loadShedder struct {
+		config    LoadSheddingConfig
+		mu        sync.Mutex
+		latencies []time.Duration
+		next      int
+		evaluated time.Time
+		fraction  float64
+		now       func() time.Time
+	}
+)
+
+func DefaultLoadSheddingConfig() LoadSheddingConfig {
+	return LoadSheddingConfig{
+		Quantile:    0.99,
+		Samples:     1000,
+		Interval:    time.Second,
+		Step:        0.1,
+		MaxFraction: 0.9,
+	}
+}
+
+func newLoadShedder(config LoadSheddingConfig, now func() time.Time) *loadShedder {
+	defaults := DefaultLoadSheddingConfig()
+	if config.Quantile <= 0 || config.Quantile > 1 {
+		config.Quantile = defaults.Quantile
+	}
+	if config.Samples <= 0 {
+		config.Samples = defaults.Samples
+	}
+	if config.Interval <= 0 {
+		config.Interval = defaults.Interval
+	}
+	if config.Step <= 0 {
+		config.Step = defaults.Step
+	}
+	if config.MaxFraction <= 0 || config.MaxFraction > 1 {
+		config.MaxFraction = defaults.MaxFraction
+	}
+
+	return &loadShedder{
+		config:    config,
+		latencies: make([]time.Duration, 0, config.Samples),
+		now:       now,
+	}
+}
+
+func (shedder *loadShedder) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		critical := shedder.config.Critical != nil && shedder.config.Critical(r)
+		if !critical && shedder.shed() {
+			w.Header().Set("Retry-After", "1")
+			writeError(w, http.StatusServiceUnavailable, "overloaded")
+			return
+		}
+
+		start := shedder.now()
+		next.ServeHTTP(w, r)
+		shedder.observe(shedder.now().Sub(start))
+	})
+}
+
+func (shedder *loadShedder) shed() bool {
+	shedder.mu.Lock()
+	shedder.evaluateIfDue()
+	fraction := shedder.fraction
+	shedder.mu.Unlock()
+
+	return fraction > 0 && rand.Float64() < fraction
+}
+
+func (shedder *loadShedder) observe(latency time.Duration) {
+	shedder.mu.Lock()
+	defer shedder.mu.Unlock()
+
+	if len(shedder.latencies) < shedder.config.Samples {
+		shedder.latencies = append(shedder.latencies, latency)
+	} else {
+		shedder.latencies[shedder.next] = latency
+		shedder.next = (shedder.next + 1) % shedder.config.Samples
+	}
+
+	shedder.evaluateIfDue()
+}
+
+// evaluateIfDue evaluates the quantile once per interval, no request having gone through since
+// the previous evaluation counting as a recovery.
+func (shedder *loadShedder) evaluateIfDue() {
+	now := shedder.now()
+	if shedder.evaluated.IsZero() {
+		shedder.evaluated = now
+	}
+	if now.Sub(shedder.evaluated) < shedder.config.Interval {
+		return
+	}
+	shedder.evaluated = now
+
+	var quantile time.Duration
+	if len(shedder.latencies) > 0 {
+		sorted := append([]time.Duration(nil), shedder.latencies...)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+		quantile = sorted[int(float64(len(sorted)-1)*shedder.config.Quantile)]
+		shedder.latencies = shedder.latencies[:0]
+		shedder.next = 0
+	}
+
+	if quantile > shedder.config.LatencyThreshold {
+		shedder.fraction += shedder.config.Step
+		if shedder.fraction > shedder.config.MaxFraction {
+			shedder.fraction = shedder.config.MaxFraction
+		}
+	} else {
+		shedder.fraction -= shedder.config.Step
+		if shedder.fraction < 0 {
+			shedder.fraction = 0
+		}
+	}
+}
@@ -0,0 +1,414 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	traceIDExemplarLabel = "trace_id"
+	otherHandlerLabel    = "other"
+)
+
+var (
+	// RouteTemplateOrOther labels the requests with the path template of their route, and those
+	// matching no route as "other". It is the default strategy.
+	RouteTemplateOrOther HandlerLabelStrategy = func(r *http.Request, route string) string {
+		if route == "" {
+			return otherHandlerLabel
+		}
+		return route
+	}
+
+	// RawPath labels the requests with their path. As clients choose the paths, the cardinality
+	// of the metrics is unbounded: it is only meant to debug a service with few distinct paths.
+	RawPath HandlerLabelStrategy = func(r *http.Request, route string) string {
+		return r.URL.Path
+	}
+)
+
+type (
+	// MetricsRecorder receives the measurements of every request served by the wrapped handler.
+	MetricsRecorder interface {
+		IncRequest(labels RequestLabels)
+		ObserveDuration(labels RequestLabels, d time.Duration)
+	}
+
+	// ResponseSizeRecorder is implemented by recorders also measuring the size of the response bodies.
+	ResponseSizeRecorder interface {
+		ObserveResponseSize(labels RequestLabels, bytes int)
+	}
+
+	// ExemplarRecorder is implemented by recorders able to link a request duration to its trace.
+	ExemplarRecorder interface {
+		ObserveDurationWithTraceID(labels RequestLabels, duration time.Duration, traceID string)
+	}
+
+	// TimeToFirstByteRecorder is implemented by recorders also measuring the time until the handler
+	// first writes the response, headers included, apart from its transfer.
+	TimeToFirstByteRecorder interface {
+		ObserveTimeToFirstByte(labels RequestLabels, d time.Duration)
+	}
+
+	// SLORecorder is implemented by recorders counting the requests of the routes with a latency
+	// objective, see WithSLO, and those slower than it.
+	SLORecorder interface {
+		IncSLORequest(labels RequestLabels, violation bool)
+	}
+
+	// HandlerErrorRecorder is implemented by recorders counting the errors returned by the HandlerFunc
+	// handlers, by kind: the category of a kurin.HTTPError or internal_error.
+	HandlerErrorRecorder interface {
+		IncHandlerError(labels RequestLabels, kind string)
+	}
+
+	// WriteErrorRecorder is implemented by recorders counting the responses whose body could not
+	// be fully written, mostly as the client went away.
+	WriteErrorRecorder interface {
+		IncWriteError(labels RequestLabels)
+	}
+
+	// ContentTypeRecorder is implemented by recorders counting the requests by media type of their
+	// body and of their response, see WithContentTypeMetrics.
+	ContentTypeRecorder interface {
+		IncContentTypes(labels RequestLabels, requestType string, responseType string)
+	}
+
+	// HandlerLabelStrategy returns the handler label of a request given the path template of its
+	// route, empty when no route matched, see WithHandlerLabelStrategy.
+	HandlerLabelStrategy func(r *http.Request, route string) string
+
+	// RequestLabels holds the labels of a request, Custom holding the ones of WithCustomLabel by name.
+	RequestLabels struct {
+		Code    string
+		Method  string
+		Handler string
+		Custom  map[string]string
+	}
+
+	// trackingRegisterer keeps the collectors registered through it, so that they can be
+	// unregistered once the adapter is closed.
+	trackingRegisterer struct {
+		prometheus.Registerer
+		collectors []prometheus.Collector
+	}
+
+	prometheusRecorder struct {
+		totalCount      *prometheus.CounterVec
+		durationHist    *prometheus.HistogramVec
+		durationSummary *prometheus.SummaryVec
+		sizeHist        *prometheus.HistogramVec
+		ttfbHist        *prometheus.HistogramVec
+		errorCount      *prometheus.CounterVec
+		sloCount        *prometheus.CounterVec
+		sloViolations   *prometheus.CounterVec
+		contentTypes    *prometheus.CounterVec
+		writeErrors     *prometheus.CounterVec
+		customLabels    []string
+	}
+)
+
+// NewPrometheusRecorder registers the request counter and duration histogram on the given registerer.
+// It is the recorder used by default, registered on prometheus.DefaultRegisterer.
+func NewPrometheusRecorder(registerer prometheus.Registerer) MetricsRecorder {
+	return newPrometheusRecorder(registerer, nil, nil)
+}
+
+func newPrometheusRecorder(registerer prometheus.Registerer, summaryObjectives map[float64]float64, customLabels []string) *prometheusRecorder {
+	labelNames := append([]string{"code", "method", "handler"}, customLabels...)
+	recorder := &prometheusRecorder{
+		customLabels: customLabels,
+		totalCount: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "app_requests_total",
+				Help: "A counter for requests to the wrapped handler.",
+			},
+			labelNames,
+		),
+		durationHist: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "app_response_duration_seconds",
+				Help:    "A histogram of request latencies.",
+				Buckets: prometheus.DefBuckets,
+			},
+			labelNames,
+		),
+		sizeHist: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "app_response_size_bytes",
+				Help:    "A histogram of response body sizes.",
+				Buckets: prometheus.ExponentialBuckets(100, 10, 7),
+			},
+			labelNames,
+		),
+		ttfbHist: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "app_response_ttfb_seconds",
+				Help:    "A histogram of the time until the first byte of the responses is written.",
+				Buckets: prometheus.DefBuckets,
+			},
+			labelNames,
+		),
+		errorCount: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "app_handler_errors_total",
+				Help: "A counter for errors returned by the handlers, by kind.",
+			},
+			[]string{"handler", "kind"},
+		),
+		sloCount: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "app_slo_requests_total",
+				Help: "A counter for requests to the routes with a latency objective.",
+			},
+			[]string{"handler"},
+		),
+		sloViolations: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "app_slo_violations_total",
+				Help: "A counter for requests slower than the latency objective of their route.",
+			},
+			[]string{"handler"},
+		),
+		contentTypes: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "app_content_types_total",
+				Help: "A counter for requests by media type of their body and of their response.",
+			},
+			[]string{"handler", "request_type", "response_type"},
+		),
+		writeErrors: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "app_response_write_errors_total",
+				Help: "A counter for responses truncated by an error writing their body.",
+			},
+			labelNames,
+		),
+	}
+	registerer.MustRegister(recorder.totalCount, recorder.durationHist, recorder.sizeHist, recorder.ttfbHist, recorder.errorCount, recorder.sloCount, recorder.sloViolations, recorder.contentTypes, recorder.writeErrors)
+
+	if summaryObjectives != nil {
+		recorder.durationSummary = prometheus.NewSummaryVec(
+			prometheus.SummaryOpts{
+				Name:       "app_response_duration_quantiles_seconds",
+				Help:       "A summary of request latencies.",
+				Objectives: summaryObjectives,
+			},
+			labelNames,
+		)
+		registerer.MustRegister(recorder.durationSummary)
+	}
+
+	return recorder
+}
+
+func (registerer *trackingRegisterer) Register(collector prometheus.Collector) error {
+	if err := registerer.Registerer.Register(collector); err != nil {
+		return err
+	}
+	registerer.collectors = append(registerer.collectors, collector)
+
+	return nil
+}
+
+func (registerer *trackingRegisterer) MustRegister(collectors ...prometheus.Collector) {
+	for _, collector := range collectors {
+		if err := registerer.Register(collector); err != nil {
+			panic(err)
+		}
+	}
+}
+
+func (registerer *trackingRegisterer) unregisterAll() {
+	for _, collector := range registerer.collectors {
+		registerer.Registerer.Unregister(collector)
+	}
+	registerer.collectors = nil
+}
+
+func (recorder *prometheusRecorder) values(labels RequestLabels) []string {
+	values := make([]string, 0, 3+len(recorder.customLabels))
+	values = append(values, labels.Code, labels.Method, labels.Handler)
+	for _, name := range recorder.customLabels {
+		values = append(values, labels.Custom[name])
+	}
+
+	return values
+}
+
+// CustomHandlerLabel labels the requests with the value returned by fn, which must be bounded.
+func CustomHandlerLabel(fn func(*http.Request) string) HandlerLabelStrategy {
+	return func(r *http.Request, route string) string {
+		return fn(r)
+	}
+}
+
+func (recorder *prometheusRecorder) IncRequest(labels RequestLabels) {
+	recorder.totalCount.WithLabelValues(recorder.values(labels)...).Inc()
+}
+
+func (recorder *prometheusRecorder) ObserveDuration(labels RequestLabels, d time.Duration) {
+	recorder.durationHist.WithLabelValues(recorder.values(labels)...).Observe(d.Seconds())
+	if recorder.durationSummary != nil {
+		recorder.durationSummary.WithLabelValues(recorder.values(labels)...).Observe(d.Seconds())
+	}
+}
+
+func (recorder *prometheusRecorder) ObserveResponseSize(labels RequestLabels, bytes int) {
+	recorder.sizeHist.WithLabelValues(recorder.values(labels)...).Observe(float64(bytes))
+}
+
+func (recorder *prometheusRecorder) ObserveTimeToFirstByte(labels RequestLabels, d time.Duration) {
+	recorder.ttfbHist.WithLabelValues(recorder.values(labels)...).Observe(d.Seconds())
+}
+
+func (recorder *prometheusRecorder) IncSLORequest(labels RequestLabels, violation bool) {
+	recorder.sloCount.WithLabelValues(labels.Handler).Inc()
+	if violation {
+		recorder.sloViolations.WithLabelValues(labels.Handler).Inc()
+	}
+}
+
+func (recorder *prometheusRecorder) IncHandlerError(labels RequestLabels, kind string) {
+	recorder.errorCount.WithLabelValues(labels.Handler, kind).Inc()
+}
+
+func (recorder *prometheusRecorder) IncWriteError(labels RequestLabels) {
+	recorder.writeErrors.WithLabelValues(recorder.values(labels)...).Inc()
+}
+
+func (recorder *prometheusRecorder) IncContentTypes(labels RequestLabels, requestType string, responseType string) {
+	recorder.contentTypes.WithLabelValues(labels.Handler, requestType, responseType).Inc()
+}
+
+// ObserveDurationWithTraceID attaches the trace ID to the histogram observation as an exemplar,
+// unless it is too long to be one.
+func (recorder *prometheusRecorder) ObserveDurationWithTraceID(labels RequestLabels, duration time.Duration, traceID string) {
+	if utf8.RuneCountInString(traceIDExemplarLabel+traceID) > prometheus.ExemplarMaxRunes || !utf8.ValidString(traceID) {
+		recorder.ObserveDuration(labels, duration)
+		return
+	}
+
+	observer := recorder.durationHist.WithLabelValues(recorder.values(labels)...)
+	observer.(prometheus.ExemplarObserver).ObserveWithExemplar(duration.Seconds(), prometheus.Labels{traceIDExemplarLabel: traceID})
+	if recorder.durationSummary != nil {
+		recorder.durationSummary.WithLabelValues(recorder.values(labels)...).Observe(duration.Seconds())
+	}
+}
+
+func (adapter *Adapter) instrument(next http.Handler) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(adapter.uninstrumented) > 0 && adapter.uninstrumented[adapter.routeTemplate(r)] {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		errorRecorder, recordErrors := adapter.metricsRecorder.(HandlerErrorRecorder)
+		var errorKind string
+		if recordErrors {
+			r = r.WithContext(context.WithValue(r.Context(), handlerErrorKey{}, &errorKind))
+		}
+
+		crw := NewCustomResponseWriter(w)
+		crw.head = r.Method == http.MethodHead
+		crw.clock = adapter.clock
+		now := adapter.clock.Now()
+		next.ServeHTTP(crw, r)
+		labels := adapter.labelsFromRequestResponse(r, crw)
+		adapter.metricsRecorder.IncRequest(labels)
+		if errorKind != "" {
+			errorRecorder.IncHandlerError(labels, errorKind)
+		}
+		if crw.Err() != nil {
+			if writeErrorRecorder, ok := adapter.metricsRecorder.(WriteErrorRecorder); ok {
+				writeErrorRecorder.IncWriteError(labels)
+			}
+		}
+		duration := adapter.clock.Now().Sub(now)
+		adapter.observeDuration(r, labels, duration)
+		adapter.logSlowRequest(labels, duration)
+		if sizeRecorder, ok := adapter.metricsRecorder.(ResponseSizeRecorder); ok {
+			sizeRecorder.ObserveResponseSize(labels, crw.size)
+		}
+		if threshold, ok := adapter.slos[labels.Handler]; ok {
+			if sloRecorder, ok := adapter.metricsRecorder.(SLORecorder); ok {
+				sloRecorder.IncSLORequest(labels, duration > threshold)
+			}
+		}
+		if adapter.contentTypes != nil {
+			if contentTypeRecorder, ok := adapter.metricsRecorder.(ContentTypeRecorder); ok {
+				contentTypeRecorder.IncContentTypes(
+					labels,
+					metricsContentType(r.Header.Get("Content-Type"), adapter.contentTypes),
+					metricsContentType(crw.Header().Get("Content-Type"), adapter.contentTypes),
+				)
+			}
+		}
+		if ttfbRecorder, ok := adapter.metricsRecorder.(TimeToFirstByteRecorder); ok {
+			// Nothing written by the handler is sent once it returns.
+			ttfb := duration
+			if !crw.firstWrite.IsZero() {
+				ttfb = crw.firstWrite.Sub(now)
+			}
+			ttfbRecorder.ObserveTimeToFirstByte(labels, ttfb)
+		}
+	})
+}
+
+func (adapter *Adapter) observeDuration(r *http.Request, labels RequestLabels, duration time.Duration) {
+	if adapter.traceID != nil {
+		if exemplarRecorder, ok := adapter.metricsRecorder.(ExemplarRecorder); ok {
+			if traceID := adapter.traceID(r.Context()); traceID != "" {
+				exemplarRecorder.ObserveDurationWithTraceID(labels, duration, traceID)
+				return
+			}
+		}
+	}
+
+	adapter.metricsRecorder.ObserveDuration(labels, duration)
+}
+
+// routeTemplate is the path template of the route of the router matching r, empty if none does.
+func (adapter *Adapter) routeTemplate(r *http.Request) string {
+	var match mux.RouteMatch
+	if !adapter.router.Match(r, &match) || match.Route == nil {
+		return ""
+	}
+	route, _ := match.Route.GetPathTemplate()
+
+	return route
+}
+
+func (adapter *Adapter) labelsFromRequestResponse(r *http.Request, crw *customResponseWriter) RequestLabels {
+	handler := adapter.handlerLabel(r, adapter.routeTemplate(r))
+
+	code := crw.statusCode
+	if errors.Is(r.Context().Err(), context.Canceled) {
+		// The client went away before the response was sent, whatever the handler wrote.
+		code = adapter.config.ClientClosedCode
+	} else if errors.Is(r.Context().Err(), context.DeadlineExceeded) && crw.firstWrite.IsZero() {
+		// The deadline of WithGlobalDeadline expired with nothing written, answered by a 503.
+		code = http.StatusServiceUnavailable
+	}
+
+	labels := RequestLabels{
+		Code:    strconv.Itoa(code),
+		Method:  r.Method,
+		Handler: handler,
+	}
+	if len(adapter.customLabels) > 0 {
+		labels.Custom = make(map[string]string, len(adapter.customLabels))
+		for _, label := range adapter.customLabels {
+			labels.Custom[label.name] = label.value(r, adapter.logger)
+		}
+	}
+
+	return labels
+}
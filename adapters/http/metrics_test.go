@@ -0,0 +1,231 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/maxperrimond/kurin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func newBenchmarkAdapter(b *testing.B, options ...Option) *Adapter {
+	b.Helper()
+
+	router := mux.NewRouter()
+	router.HandleFunc("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+	options = append([]Option{WithRegistry(prometheus.NewRegistry())}, options...)
+
+	return NewHTTPAdapter(router, nil, "", 0, "test", kurin.NewStdLogger(io.Discard, kurin.LevelError), options...).(*Adapter)
+}
+
+func benchmarkServe(b *testing.B, adapter *Adapter) {
+	r := httptest.NewRequest(http.MethodGet, "/users/1", nil)
+	w := httptest.NewRecorder()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		adapter.srv.Handler.ServeHTTP(w, r)
+	}
+}
+
+func BenchmarkInstrumentation(b *testing.B) {
+	b.Run("metrics", func(b *testing.B) {
+		benchmarkServe(b, newBenchmarkAdapter(b))
+	})
+	b.Run("without metrics", func(b *testing.B) {
+		benchmarkServe(b, newBenchmarkAdapter(b, WithoutMetrics()))
+	})
+	b.Run("uninstrumented route", func(b *testing.B) {
+		benchmarkServe(b, newBenchmarkAdapter(b, WithoutInstrumentation("/users/{id}")))
+	})
+}
+
+func BenchmarkRequestCounter(b *testing.B) {
+	labels := RequestLabels{Code: "200", Method: http.MethodGet, Handler: "/users/{id}"}
+
+	b.Run("labels map", func(b *testing.B) {
+		recorder := newPrometheusRecorder(prometheus.NewRegistry(), nil, nil)
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			recorder.totalCount.With(prometheus.Labels{
+				"code":    labels.Code,
+				"method":  labels.Method,
+				"handler": labels.Handler,
+			}).Inc()
+		}
+	})
+	b.Run("label values", func(b *testing.B) {
+		recorder := newPrometheusRecorder(prometheus.NewRegistry(), nil, nil)
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			recorder.IncRequest(labels)
+		}
+	})
+}
+
+func TestClientClosedCodeWithoutDefaultConfig(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	router := mux.NewRouter()
+	router.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	})
+	adapter := NewHTTPAdapterFromConfig(router, nil, Config{}, kurin.NewStdLogger(io.Discard, kurin.LevelError), WithRegistry(registry)).(*Adapter)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	adapter.srv.Handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/slow", nil).WithContext(ctx))
+
+	expected := `
+# HELP app_requests_total A counter for requests to the wrapped handler.
+# TYPE app_requests_total counter
+app_requests_total{code="499",handler="/slow",method="GET"} 1
+`
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(expected), "app_requests_total"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestHeadResponseSize(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	router := mux.NewRouter()
+	router.HandleFunc("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ignored for HEAD"))
+	})
+	adapter := newTestAdapter(t, router, WithRegistry(registry))
+
+	adapter.srv.Handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodHead, "/users/1", nil))
+
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var found bool
+	for _, family := range families {
+		if family.GetName() != "app_response_size_bytes" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			found = true
+			labels := map[string]string{}
+			for _, pair := range metric.GetLabel() {
+				labels[pair.GetName()] = pair.GetValue()
+			}
+			if labels["code"] != "200" || labels["method"] != http.MethodHead {
+				t.Errorf("got labels %v, want code 200 and method HEAD", labels)
+			}
+			if histogram := metric.GetHistogram(); histogram.GetSampleCount() != 1 || histogram.GetSampleSum() != 0 {
+				t.Errorf("got %d samples summing %v bytes, want 1 sample of 0 bytes", histogram.GetSampleCount(), histogram.GetSampleSum())
+			}
+		}
+	}
+	if !found {
+		t.Fatal("app_response_size_bytes not recorded")
+	}
+
+	expected := `
+# HELP app_requests_total A counter for requests to the wrapped handler.
+# TYPE app_requests_total counter
+app_requests_total{code="200",handler="/users/{id}",method="HEAD"} 1
+`
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(expected), "app_requests_total"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestWithoutInstrumentation(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	var wrapped bool
+	router := mux.NewRouter()
+	router.HandleFunc("/proxy/{rest:.*}", func(w http.ResponseWriter, r *http.Request) {
+		_, wrapped = w.(*customResponseWriter)
+	})
+	adapter := newTestAdapter(t, router, WithRegistry(registry), WithoutInstrumentation("/proxy/{rest:.*}"))
+
+	adapter.srv.Handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/proxy/users/1", nil))
+
+	if wrapped {
+		t.Error("the response writer of an uninstrumented route is wrapped")
+	}
+	if count, err := testutil.GatherAndCount(registry, "app_requests_total"); err != nil || count != 0 {
+		t.Errorf("got %d requests recorded (%v), want none", count, err)
+	}
+}
+
+func TestCloseUnregistersMetrics(t *testing.T) {
+	for i := 0; i < 3; i++ {
+		adapter := NewHTTPAdapter(mux.NewRouter(), nil, "127.0.0.1", 0, "test", kurin.NewStdLogger(io.Discard, kurin.LevelError)).(*Adapter)
+		startTestAdapter(t, adapter)
+		adapter.srv.Handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+		if err := adapter.CloseContext(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if count, err := testutil.GatherAndCount(prometheus.DefaultGatherer, "app_requests_total", "app_start_time_seconds"); err != nil || count != 0 {
+		t.Errorf("got %d metrics left on the default registry (%v), want none", count, err)
+	}
+}
+
+// brokenPipeWriter accepts the first limit bytes of the body, failing like a client gone away after.
+type brokenPipeWriter struct {
+	*httptest.ResponseRecorder
+	limit int
+}
+
+func (w *brokenPipeWriter) Write(b []byte) (int, error) {
+	if len(b) > w.limit {
+		n, _ := w.ResponseRecorder.Write(b[:w.limit])
+		w.limit = 0
+		return n, errors.New("write: broken pipe")
+	}
+	w.limit -= len(b)
+
+	return w.ResponseRecorder.Write(b)
+}
+
+func TestWriteErrorAccounting(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	router := mux.NewRouter()
+	router.HandleFunc("/download", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello"))
+		if _, err := w.Write([]byte(" world")); err == nil {
+			t.Error("got no error, want the write error of the client")
+		}
+	})
+	adapter := newTestAdapter(t, router, WithRegistry(registry))
+
+	adapter.srv.Handler.ServeHTTP(&brokenPipeWriter{httptest.NewRecorder(), 8}, httptest.NewRequest(http.MethodGet, "/download", nil))
+
+	expected := `
+# HELP app_response_write_errors_total A counter for responses truncated by an error writing their body.
+# TYPE app_response_write_errors_total counter
+app_response_write_errors_total{code="200",handler="/download",method="GET"} 1
+`
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(expected), "app_response_write_errors_total"); err != nil {
+		t.Error(err)
+	}
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, family := range families {
+		if family.GetName() != "app_response_size_bytes" {
+			continue
+		}
+		if sum := family.GetMetric()[0].GetHistogram().GetSampleSum(); sum != 8 {
+			t.Errorf("got %v bytes, want the 8 bytes written before the error", sum)
+		}
+		return
+	}
+	t.Fatal("app_response_size_bytes not recorded")
+}
@@ -0,0 +1,100 @@
+package http
+
+import (
+	"fmt"
+	"strings"
+)
+
+type (
+	// MiddlewareSet is a set of the built-in middlewares, enabled with their default settings by
+	// WithMiddlewares or the Middlewares setting of Config, written as a comma separated list of
+	// their names in configuration files: "request_id,security_headers". The middlewares needing
+	// settings without default, like a store, a list of hosts or a latency threshold, are only
+	// enabled by their option.
+	MiddlewareSet uint
+)
+
+const (
+	// MiddlewareRequestID is the middleware of WithRequestID.
+	MiddlewareRequestID MiddlewareSet = 1 << iota
+	// MiddlewareSecurityHeaders is the middleware of WithSecurityHeaders with DefaultSecurityHeadersConfig.
+	MiddlewareSecurityHeaders
+	// MiddlewareRecovery is the recovery of the panics, see WithRecoveryHandler. It is always
+	// enabled, outermost, and only part of the set for configurations to list the whole chain.
+	MiddlewareRecovery
+	// MiddlewareRequestDecompression is the middleware of WithRequestDecompression, up to 10MB.
+	MiddlewareRequestDecompression
+	// MiddlewareETag is the middleware of WithETag, for responses up to 1MB.
+	MiddlewareETag
+)
+
+const (
+	defaultDecompressionMaxBytes = 10 << 20
+	defaultETagMaxBytes          = 1 << 20
+)
+
+// middlewareNames lists the built-in middlewares in their canonical order, the first being the outermost.
+var middlewareNames = []struct {
+	middleware MiddlewareSet
+	name       string
+}{
+	{MiddlewareRecovery, "recovery"},
+	{MiddlewareRequestID, "request_id"},
+	{MiddlewareSecurityHeaders, "security_headers"},
+	{MiddlewareRequestDecompression, "request_decompression"},
+	{MiddlewareETag, "etag"},
+}
+
+// middlewares returns the middlewares of the set, in the canonical order.
+func (set MiddlewareSet) middlewares(adapter *Adapter) []Middleware {
+	var middlewares []Middleware
+	if set&MiddlewareRequestID != 0 {
+		middlewares = append(middlewares, adapter.requestIDMiddleware)
+	}
+	if set&MiddlewareSecurityHeaders != 0 {
+		middlewares = append(middlewares, securityHeadersMiddleware(DefaultSecurityHeadersConfig()))
+	}
+	if set&MiddlewareRequestDecompression != 0 {
+		middlewares = append(middlewares, decompressionMiddleware(defaultDecompressionMaxBytes))
+	}
+	if set&MiddlewareETag != 0 {
+		middlewares = append(middlewares, etagMiddleware(defaultETagMaxBytes))
+	}
+
+	return middlewares
+}
+
+func (set MiddlewareSet) MarshalText() ([]byte, error) {
+	var names []string
+	for _, m := range middlewareNames {
+		if set&m.middleware != 0 {
+			names = append(names, m.name)
+		}
+	}
+
+	return []byte(strings.Join(names, ",")), nil
+}
+
+func (set *MiddlewareSet) UnmarshalText(text []byte) error {
+	*set = 0
+	for _, name := range strings.Split(string(text), ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+
+		found := false
+		for _, m := range middlewareNames {
+			if m.name == name {
+				*set |= m.middleware
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("unknown middleware %q", name)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,148 @@
+package http
+
+import (
+	"errors"
+	"io"
+	"math"
+	"mime/multipart"
+	"net/http"
+)
+
+const (
+	// multipartMaxValues is the number of non file fields ParseMultipart accepts beside the files.
+	multipartMaxValues = 100
+	// multipartHeadersRoom is what the body may hold beside the files and the fields kept in
+	// memory, for the headers of the parts.
+	multipartHeadersRoom = 1 << 20
+)
+
+var errTooManyParts = errors.New("multipart: too many parts")
+
+type parsedForm struct {
+	form *multipart.Form
+	err  error
+}
+
+// ParseMultipart parses the multipart body of r like r.ParseMultipartForm, refusing more than
+// maxFiles files, files above maxFileSize and more than multipartMaxValues other fields. The
+// parts are counted and the files measured while the body is read, so that a body made of many
+// tiny parts or a file too large is refused before being parsed or buffered on disk whole. The
+// returned *DecodeError renders as a 413, or a 400 for a malformed body, through WriteError.
+func ParseMultipart(r *http.Request, maxMemory int64, maxFiles int, maxFileSize int64) error {
+	r.Body = http.MaxBytesReader(nil, r.Body, multipartMaxBytes(maxMemory, maxFiles, maxFileSize))
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return newMultipartError(err)
+	}
+
+	// The parts checked are written again to the multipart.Reader building the form.
+	pr, pw := io.Pipe()
+	writer := multipart.NewWriter(pw)
+	parsed := make(chan parsedForm, 1)
+	go func() {
+		form, err := multipart.NewReader(pr, writer.Boundary()).ReadForm(maxMemory)
+		// Stops the writes once the form is read, or could not be.
+		pr.CloseWithError(io.ErrClosedPipe)
+		parsed <- parsedForm{form, err}
+	}()
+
+	err = copyMultipart(reader, writer, maxFiles, maxFileSize)
+	if err == nil {
+		err = writer.Close()
+	}
+	pw.CloseWithError(err)
+	result := <-parsed
+
+	var decodeErr *DecodeError
+	switch {
+	case errors.As(err, &decodeErr):
+		if result.form != nil {
+			result.form.RemoveAll()
+		}
+		return decodeErr
+	case result.err != nil:
+		return newMultipartError(result.err)
+	case err != nil:
+		result.form.RemoveAll()
+		return newMultipartError(err)
+	}
+
+	if err := r.ParseForm(); err != nil {
+		result.form.RemoveAll()
+		return &DecodeError{MalformedMultipart, err}
+	}
+	for k, v := range result.form.Value {
+		r.Form[k] = append(r.Form[k], v...)
+		r.PostForm[k] = append(r.PostForm[k], v...)
+	}
+	r.MultipartForm = result.form
+
+	return nil
+}
+
+// copyMultipart copies the parts of reader to writer, failing with a *DecodeError as soon as there
+// are too many of them or a file is too large.
+func copyMultipart(reader *multipart.Reader, writer *multipart.Writer, maxFiles int, maxFileSize int64) error {
+	files, values := 0, 0
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		limit := int64(math.MaxInt64)
+		if part.FileName() != "" {
+			if files++; files > maxFiles {
+				return &DecodeError{TooManyParts, errors.New("multipart: too many files")}
+			}
+			limit = maxFileSize
+		} else if values++; values > multipartMaxValues {
+			return &DecodeError{TooManyParts, errTooManyParts}
+		}
+
+		w, err := writer.CreatePart(part.Header)
+		if err != nil {
+			return err
+		}
+		if limit < math.MaxInt64 {
+			n, err := io.Copy(w, io.LimitReader(part, limit+1))
+			if err != nil {
+				return err
+			}
+			if n > limit {
+				return &DecodeError{FileTooLarge, errors.New("multipart: file " + part.FileName() + " too large")}
+			}
+		} else if _, err := io.Copy(w, part); err != nil {
+			return err
+		}
+	}
+}
+
+// multipartMaxBytes bounds the body to the files, the fields kept in memory and the headers of the
+// parts, saturating instead of overflowing.
+func multipartMaxBytes(maxMemory int64, maxFiles int, maxFileSize int64) int64 {
+	if maxMemory > math.MaxInt64-multipartHeadersRoom {
+		return math.MaxInt64
+	}
+	rest := math.MaxInt64 - multipartHeadersRoom - maxMemory
+	if maxFiles > 0 && maxFileSize > rest/int64(maxFiles) {
+		return math.MaxInt64
+	}
+
+	return maxMemory + multipartHeadersRoom + int64(maxFiles)*maxFileSize
+}
+
+func newMultipartError(err error) *DecodeError {
+	var maxBytesErr *http.MaxBytesError
+	switch {
+	case errors.Is(err, errTooManyParts):
+		return &DecodeError{TooManyParts, errTooManyParts}
+	case errors.As(err, &maxBytesErr), errors.Is(err, multipart.ErrMessageTooLarge):
+		return &DecodeError{BodyTooLarge, err}
+	}
+
+	return &DecodeError{MalformedMultipart, err}
+}
@@ -0,0 +1,134 @@
+package http
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// endlessReader is an endless file, refused before being read whole.
+type endlessReader struct {
+	read int64
+}
+
+func (reader *endlessReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 'a'
+	}
+	reader.read += int64(len(p))
+
+	return len(p), nil
+}
+
+func newMultipartRequest(t *testing.T, write func(writer *multipart.Writer)) *http.Request {
+	t.Helper()
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	write(writer)
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	r := httptest.NewRequest(http.MethodPost, "/upload?source=test", &body)
+	r.Header.Set("Content-Type", writer.FormDataContentType())
+
+	return r
+}
+
+func TestParseMultipart(t *testing.T) {
+	r := newMultipartRequest(t, func(writer *multipart.Writer) {
+		writer.WriteField("name", "avatar")
+		w, _ := writer.CreateFormFile("file", "avatar.png")
+		w.Write([]byte("png"))
+	})
+	if err := ParseMultipart(r, 1<<10, 1, 1<<10); err != nil {
+		t.Fatal(err)
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	if name, source := r.FormValue("name"), r.FormValue("source"); name != "avatar" || source != "test" {
+		t.Errorf("got %q and %q, want the field and the query", name, source)
+	}
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if content, _ := io.ReadAll(file); string(content) != "png" || header.Filename != "avatar.png" {
+		t.Errorf("got %s %q, want avatar.png png", header.Filename, content)
+	}
+}
+
+func TestParseMultipartLimits(t *testing.T) {
+	tests := []struct {
+		name  string
+		write func(writer *multipart.Writer)
+		kind  DecodeErrorKind
+	}{
+		{"too many files", func(writer *multipart.Writer) {
+			for i := 0; i < 3; i++ {
+				w, _ := writer.CreateFormFile("file", "file.txt")
+				w.Write([]byte("file"))
+			}
+		}, TooManyParts},
+		{"too many fields", func(writer *multipart.Writer) {
+			for i := 0; i <= multipartMaxValues; i++ {
+				writer.WriteField("field", "value")
+			}
+		}, TooManyParts},
+		{"file too large", func(writer *multipart.Writer) {
+			w, _ := writer.CreateFormFile("file", "file.txt")
+			w.Write([]byte(strings.Repeat("a", 11)))
+		}, FileTooLarge},
+	}
+
+	for _, test := range tests {
+		r := newMultipartRequest(t, test.write)
+		var decodeErr *DecodeError
+		if err := ParseMultipart(r, 1<<10, 2, 10); !errors.As(err, &decodeErr) || decodeErr.Kind != test.kind {
+			t.Errorf("%s: got %v, want a %s error", test.name, err, test.kind)
+		}
+	}
+}
+
+func TestParseMultipartMeasuresFilesWhileReading(t *testing.T) {
+	file := &endlessReader{}
+	pr, pw := io.Pipe()
+	writer := multipart.NewWriter(pw)
+	written := make(chan struct{})
+	go func() {
+		defer close(written)
+		w, _ := writer.CreateFormFile("file", "endless.txt")
+		io.Copy(w, file)
+	}()
+	r := httptest.NewRequest(http.MethodPost, "/upload", pr)
+	r.Header.Set("Content-Type", writer.FormDataContentType())
+
+	var decodeErr *DecodeError
+	if err := ParseMultipart(r, 1<<10, 1, 1<<20); !errors.As(err, &decodeErr) || decodeErr.Kind != FileTooLarge {
+		t.Fatalf("got %v, want a file_too_large error", err)
+	}
+	pr.Close()
+	<-written
+	if file.read > 2<<20 {
+		t.Errorf("got %d bytes read, want the file refused once above the limit", file.read)
+	}
+}
+
+func TestMultipartMaxBytesSaturates(t *testing.T) {
+	if limit := multipartMaxBytes(1<<20, 10, math.MaxInt64/4); limit != math.MaxInt64 {
+		t.Errorf("got %d, want the limit saturated", limit)
+	}
+	if limit := multipartMaxBytes(math.MaxInt64, 0, 0); limit != math.MaxInt64 {
+		t.Errorf("got %d, want the limit saturated", limit)
+	}
+	if limit := multipartMaxBytes(1<<10, 2, 1<<10); limit != 3<<10+multipartHeadersRoom {
+		t.Errorf("got %d, want the files, the memory and the headers room", limit)
+	}
+}
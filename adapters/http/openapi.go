@@ -0,0 +1,84 @@
+package http
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"path"
+)
+
+var swaggerUITemplate = template.Must(template.New("swagger-ui").Parse(`<!DOCTYPE html>
+<html>
+<head>
+  <meta charset="utf-8">
+  <title>API documentation</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js"></script>
+  <script nonce="{{.Nonce}}">
+    window.onload = function() {
+      SwaggerUIBundle({url: {{.SpecPath}}, dom_id: "#swagger-ui"});
+    };
+  </script>
+</body>
+</html>
+`))
+
+// swaggerUIContentSecurityPolicy replaces the one of the security headers on the Swagger UI page only,
+// letting it load its assets from unpkg and run its inline script, allowed by its nonce.
+const swaggerUIContentSecurityPolicy = "default-src 'none'; script-src https://unpkg.com 'nonce-%s'; " +
+	"style-src https://unpkg.com 'unsafe-inline'; img-src 'self' data:; connect-src 'self'; frame-ancestors 'none'"
+
+// WithOpenAPI serves the OpenAPI spec read from the named file of spec at specPath, and a Swagger UI
+// rendering it at uiPath unless empty. The spec can be embedded (embed.FS) or read from the disk
+// (os.DirFS). Both are routes of the router, so they are instrumented, behind the given middlewares.
+//
+// The Swagger UI assets are not embedded but loaded by the browser from the unpkg CDN, so the UI
+// needs access to it and the page relaxes the Content-Security-Policy accordingly, on its route
+// only. Air-gapped deployments should only serve the spec, leaving uiPath empty.
+func WithOpenAPI(specPath string, uiPath string, spec fs.FS, name string, middlewares ...Middleware) Option {
+	return func(adapter *Adapter) {
+		adapter.Handle(specPath, openAPISpecHandler(spec, name), middlewares...).Methods(http.MethodGet, http.MethodHead)
+		if uiPath != "" {
+			adapter.Handle(uiPath, swaggerUIHandler(specPath), middlewares...).Methods(http.MethodGet, http.MethodHead)
+		}
+	}
+}
+
+func openAPISpecHandler(spec fs.FS, name string) http.Handler {
+	contentType := "application/json"
+	if ext := path.Ext(name); ext == ".yaml" || ext == ".yml" {
+		contentType = "application/yaml"
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		content, err := fs.ReadFile(spec, name)
+		if err != nil {
+			WriteError(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", contentType)
+		w.Write(content)
+	})
+}
+
+func swaggerUIHandler(specPath string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		nonce := make([]byte, 16)
+		if _, err := rand.Read(nonce); err != nil {
+			WriteError(w, err)
+			return
+		}
+		data := struct{ SpecPath, Nonce string }{specPath, base64.StdEncoding.EncodeToString(nonce)}
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Content-Security-Policy", fmt.Sprintf(swaggerUIContentSecurityPolicy, data.Nonce))
+		swaggerUITemplate.Execute(w, data)
+	})
+}
@@ -0,0 +1,579 @@
+package http
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/maxperrimond/kurin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// WithName names the adapter, to tell it apart from the other adapters of the application: its
+// log entries get an adapter field and its metrics an adapter label with the name.
+func WithName(name string) Option {
+	return func(adapter *Adapter) {
+		adapter.config.Name = name
+	}
+}
+
+// WithNotFoundHandler replaces the JSON error returned when no route of the router matches.
+func WithNotFoundHandler(h http.Handler) Option {
+	return func(adapter *Adapter) {
+		adapter.notFoundHandler = h
+	}
+}
+
+// WithMethodNotAllowedHandler replaces the JSON error returned when a route matches but not its method.
+func WithMethodNotAllowedHandler(h http.Handler) Option {
+	return func(adapter *Adapter) {
+		adapter.methodNotAllowedHandler = h
+	}
+}
+
+// WithRecoveryHandler replaces the JSON error returned when the handler or a middleware panics
+// before starting the response, which is aborted otherwise.
+func WithRecoveryHandler(h http.Handler) Option {
+	return func(adapter *Adapter) {
+		adapter.recoveryHandler = h
+	}
+}
+
+// WithInternalPaths mounts the health, version and metrics endpoints on the given paths instead of
+// /health, /version and /metrics. An empty path disables the endpoint, its requests reaching the
+// wrapped handler like any other.
+func WithInternalPaths(health string, version string, metrics string) Option {
+	return func(adapter *Adapter) {
+		adapter.config.HealthPath = health
+		adapter.config.VersionPath = version
+		adapter.config.MetricsPath = metrics
+	}
+}
+
+// WithProbePaths mounts the liveness and readiness endpoints on the given paths, e.g. /live and
+// /ready, in front of the router. They are not mounted by default, and an empty path disables the
+// endpoint.
+func WithProbePaths(live string, ready string) Option {
+	return func(adapter *Adapter) {
+		adapter.config.LivePath = live
+		adapter.config.ReadyPath = ready
+	}
+}
+
+// WithoutMetrics disables the /metrics endpoint and the request instrumentation, so requests reach
+// the handler without any response writer wrapping or label computation.
+func WithoutMetrics() Option {
+	return func(adapter *Adapter) {
+		adapter.config.DisableMetrics = true
+	}
+}
+
+// WithMetricsRecorder sends the request metrics to the given recorder instead of Prometheus.
+// The /metrics endpoint is only mounted for the default Prometheus recorder.
+func WithMetricsRecorder(recorder MetricsRecorder) Option {
+	return func(adapter *Adapter) {
+		adapter.metricsRecorder = recorder
+	}
+}
+
+// WithHandlerLabelStrategy sets how the handler label of the request metrics is computed,
+// RouteTemplateOrOther by default. The latency objectives of WithSLO are looked up by this label.
+func WithHandlerLabelStrategy(strategy HandlerLabelStrategy) Option {
+	return func(adapter *Adapter) {
+		adapter.handlerLabel = strategy
+	}
+}
+
+// WithoutInstrumentation serves the requests to the routes of the given path templates, e.g.
+// "/proxy/{rest:.*}", without wrapping the response writer nor recording metrics, for hot routes on
+// which it is a measurable overhead.
+func WithoutInstrumentation(routes ...string) Option {
+	return func(adapter *Adapter) {
+		if adapter.uninstrumented == nil {
+			adapter.uninstrumented = make(map[string]bool, len(routes))
+		}
+		for _, route := range routes {
+			adapter.uninstrumented[route] = true
+		}
+	}
+}
+
+// WithPreStopDelay makes Close keep serving for the given delay before shutting the server down,
+// leaving time to the load balancer (e.g. the Kubernetes endpoints controller) to stop routing
+// traffic to it. The adapter is reported as unavailable on the health and readiness endpoints from
+// the start of Close, with or without delay.
+func WithPreStopDelay(d time.Duration) Option {
+	return func(adapter *Adapter) {
+		adapter.config.PreStopDelay = Duration(d)
+	}
+}
+
+// WithShutdownTimeout bounds the graceful shutdown of the server when closed by the application,
+// the pre-stop delay included, before the remaining connections are forced closed.
+func WithShutdownTimeout(d time.Duration) Option {
+	return func(adapter *Adapter) {
+		adapter.config.ShutdownTimeout = Duration(d)
+	}
+}
+
+// WithTCPKeepAlive sets the TCP keep-alive period of the accepted connections, e.g. to match the
+// idle timeout of a load balancer, or disables keep-alives when 0.
+func WithTCPKeepAlive(d time.Duration) Option {
+	return func(adapter *Adapter) {
+		adapter.tcpKeepAlive = &d
+	}
+}
+
+// WithReadHeaderTimeout sets how long the server waits for the headers of a request, 5s by default.
+func WithReadHeaderTimeout(d time.Duration) Option {
+	return func(adapter *Adapter) {
+		adapter.config.ReadHeaderTimeout = Duration(d)
+	}
+}
+
+// WithSecurityHeaders sets the given security headers on every response of the wrapped handler,
+// before it is called so it is still able to override them. Start from DefaultSecurityHeadersConfig.
+func WithSecurityHeaders(config SecurityHeadersConfig) Option {
+	return func(adapter *Adapter) {
+		adapter.middlewares = append(adapter.middlewares, securityHeadersMiddleware(config))
+	}
+}
+
+// WithRegistry registers the adapter metrics on the given registry, served on the metrics endpoint,
+// instead of the global Prometheus one. This allows several adapters in the same process.
+func WithRegistry(registry *prometheus.Registry) Option {
+	return func(adapter *Adapter) {
+		adapter.registry = registry
+	}
+}
+
+// WithMetricsHandler serves the metrics endpoint with h instead of the promhttp handler, e.g. to add
+// caching or authentication. It should gather the registry given to WithRegistry, or the default one.
+func WithMetricsHandler(h http.Handler) Option {
+	return func(adapter *Adapter) {
+		adapter.metricsHandler = h
+	}
+}
+
+// WithMetricsHandlerOpts sets the options of the promhttp handler serving the metrics endpoint,
+// e.g. to enable the OpenMetrics format, cap the concurrent scrapes or choose how the gathering
+// errors are reported. OpenMetrics is always enabled with WithExemplars.
+func WithMetricsHandlerOpts(opts promhttp.HandlerOpts) Option {
+	return func(adapter *Adapter) {
+		adapter.metricsHandlerOpts = opts
+	}
+}
+
+// WithDurationSummary also observes the request durations into the app_response_duration_quantiles_seconds
+// summary with the given objectives (quantile: absolute error), e.g. {0.5: 0.05, 0.9: 0.01, 0.99: 0.001}.
+//
+// Unlike the histogram, quantiles are computed by the process itself: they are exact for the instance
+// and cheap to query, but cannot be aggregated across instances and cost more to observe. The histogram
+// stays the metric to use for fleet wide latencies. Ignored when a custom metrics recorder is used.
+func WithDurationSummary(objectives map[float64]float64) Option {
+	return func(adapter *Adapter) {
+		adapter.summaryObjectives = objectives
+	}
+}
+
+// WithClientClosedCode sets the code label of requests abandoned by their client, 499 by default
+// following the nginx convention.
+func WithClientClosedCode(code int) Option {
+	return func(adapter *Adapter) {
+		adapter.config.ClientClosedCode = code
+	}
+}
+
+// WithEnforceContentType answers 415 to POST, PUT and PATCH requests with a body whose media type,
+// parameters such as charset aside, is not one of the given types, or which have no Content-Type.
+func WithEnforceContentType(types ...string) Option {
+	return func(adapter *Adapter) {
+		adapter.middlewares = append(adapter.middlewares, contentTypeMiddleware(types))
+	}
+}
+
+// WithAdmin mounts the admin endpoints under the given path (e.g. /admin/routes listing the routes
+// of the router), behind the given middlewares which should at least authenticate the requests.
+func WithAdmin(path string, middlewares ...Middleware) Option {
+	return func(adapter *Adapter) {
+		adapter.config.AdminPath = path
+		adapter.adminMiddlewares = middlewares
+	}
+}
+
+// WithDebugVars serves the expvar variables at /debug/vars under the admin path set by WithAdmin,
+// and exposes the build information (see ReadBuildInfo) as the app_build_info metric.
+func WithDebugVars() Option {
+	return func(adapter *Adapter) {
+		adapter.debugVars = true
+	}
+}
+
+// WithFailureHistory keeps the last size failures reported to the adapter, through OnFailure or
+// MarkUnhealthy, and lists them with their time, the most recent first, at the errors endpoint of
+// the admin, see WithAdmin.
+func WithFailureHistory(size int) Option {
+	return func(adapter *Adapter) {
+		if size > 0 {
+			adapter.failures = newFailureHistory(size)
+		}
+	}
+}
+
+// WithAllowedHosts answers 421 to requests whose Host header, port aside, is not one of the given
+// hosts, and 400 to those without a valid one. A host starting with "*." allows any of its subdomains.
+// The internal endpoints are not checked so that probes using the pod IP keep working.
+func WithAllowedHosts(hosts []string) Option {
+	return func(adapter *Adapter) {
+		adapter.middlewares = append(adapter.middlewares, allowedHostsMiddleware(hosts))
+	}
+}
+
+// WithExemplars attaches the trace ID returned by traceID for the request context, when not empty,
+// as an exemplar to the duration histogram, and enables the OpenMetrics format on the metrics
+// endpoint to expose them. The recorder set by WithMetricsRecorder gets it if it implements
+// ExemplarRecorder.
+func WithExemplars(traceID func(ctx context.Context) string) Option {
+	return func(adapter *Adapter) {
+		adapter.traceID = traceID
+	}
+}
+
+// WithRequestIDGenerator sets how WithRequestID generates the missing request IDs, e.g. as UUIDv7,
+// ULID or KSUID, instead of random UUIDs. The generator is called concurrently.
+func WithRequestIDGenerator(generator func() string) Option {
+	return func(adapter *Adapter) {
+		adapter.requestIDGenerator = generator
+	}
+}
+
+// WithWarmup starts the adapter warming up: until MarkWarmedUp is called, the application requests
+// are answered 503 with a Retry-After header of the given delay, while the internal endpoints such
+// as the health probe are served.
+func WithWarmup(retryAfter time.Duration) Option {
+	return func(adapter *Adapter) {
+		adapter.warming = 1
+		adapter.middlewares = append(adapter.middlewares, adapter.warmupMiddleware(retryAfter))
+	}
+}
+
+// WithBodyCapture logs at debug level the request and response bodies of the configured routes, up
+// to a size cap and through the redaction hook, to diagnose a client. It is meant to be enabled
+// temporarily: the bodies may hold personal data.
+func WithBodyCapture(config BodyCaptureConfig) Option {
+	return func(adapter *Adapter) {
+		adapter.middlewares = append(adapter.middlewares, adapter.bodyCaptureMiddleware(config))
+	}
+}
+
+// WithHealthCheck runs the check on every health and readiness probe, which then answer a JSON
+// report of the checks. A failing critical check makes the adapter unhealthy and not ready (503), a
+// failing non critical one keeps it healthy and ready but reported as degraded, and by the
+// app_health_degraded metric. The liveness probe runs no check. The checks run
+// concurrently and should return once their context is done, see WithHealthTimeout. They are
+// added to the health registry of the application the adapter is given to, a name being used
+// once: OpenContext, or the application Run, fails with kurin.ErrDuplicateHealthCheck otherwise.
+func WithHealthCheck(name string, critical bool, check func(ctx context.Context) error) Option {
+	return func(adapter *Adapter) {
+		healthCheck := kurin.HealthCheck{Name: name, Critical: critical, Check: check}
+		if err := adapter.healthRegistry.Register(healthCheck); err != nil {
+			if adapter.configErr == nil {
+				adapter.configErr = err
+			}
+			return
+		}
+		adapter.ownHealthChecks = append(adapter.ownHealthChecks, healthCheck)
+	}
+}
+
+// WithRequestID reuses the X-Request-ID header of the requests, or generates one, sends it back and
+// puts it in the request context along a logger decorated with it, and with the trace ID when
+// WithExemplars is used, as returned by kurin.LoggerFromContext. Give it before the other options
+// adding middlewares so that they get them too.
+func WithRequestID() Option {
+	return func(adapter *Adapter) {
+		adapter.middlewares = append(adapter.middlewares, adapter.requestIDMiddleware)
+	}
+}
+
+// WithLoadShedding answers 503 to a growing fraction of the non critical requests while the latency
+// quantile of the latest ones exceeds the configured threshold, and lowers it back once the latency
+// recovered. The internal endpoints are never shed.
+func WithLoadShedding(config LoadSheddingConfig) Option {
+	return func(adapter *Adapter) {
+		shedder := newLoadShedder(config, func() time.Time {
+			return adapter.clock.Now()
+		})
+		adapter.middlewares = append(adapter.middlewares, shedder.middleware)
+	}
+}
+
+// WithDistributedRateLimit limits the requests to rps per second, with bursts of burst requests,
+// by client IP or the key of WithRateLimitKey, across the replicas sharing the store. The requests
+// above the limit are answered 429 with a Retry-After header. The requests are allowed while the
+// store is unavailable or slower than 100ms, the failure being logged once until it recovers.
+func WithDistributedRateLimit(store RateLimitStore, rps float64, burst int) Option {
+	return func(adapter *Adapter) {
+		adapter.middlewares = append(adapter.middlewares, adapter.rateLimitMiddleware(store, rps, burst))
+	}
+}
+
+// WithRateLimitKey sets the key the requests are rate limited by, e.g. an API key or the client IP
+// forwarded by a trusted proxy, instead of the IP of the peer.
+func WithRateLimitKey(fn func(*http.Request) string) Option {
+	return func(adapter *Adapter) {
+		adapter.rateLimitKey = fn
+	}
+}
+
+// WithListener serves on the given listener, e.g. from systemd socket activation or wrapping another
+// one, instead of listening on the configured host and port which are then ignored. The caller owns
+// the listener: it creates and configures it, and closes it once done, the adapter stopping to
+// accept connections on it when shut down but leaving it open.
+func WithListener(l net.Listener) Option {
+	return func(adapter *Adapter) {
+		adapter.listener = l
+	}
+}
+
+// WithSingleflight lets concurrent identical GET requests, same URL, credentials (Authorization
+// and Cookie headers) and negotiation (Accept and Accept-Encoding headers), share a single execution
+// of the handler and its response. It is meant for expensive idempotent endpoints. Responses larger
+// than maxBytes, flushed by the handler, or answered to a cancelled request are not shared: the
+// requests waiting for them run the handler themselves.
+func WithSingleflight(maxBytes int) Option {
+	return func(adapter *Adapter) {
+		adapter.middlewares = append(adapter.middlewares, singleflightMiddleware(maxBytes))
+	}
+}
+
+// WithTLSConfig serves HTTPS with the given configuration, which must hold the server certificates.
+func WithTLSConfig(config *tls.Config) Option {
+	return func(adapter *Adapter) {
+		adapter.tlsConfig = config.Clone()
+	}
+}
+
+// WithMaxConcurrentHandshakes bounds the TLS handshakes in progress to n, protecting the CPU from a
+// handshake flood the load shedding cannot see, as it only applies to the requests. The other
+// connections wait for their turn, and are closed once they waited or took longer than the
+// shortest of the read header, read and write timeouts. It requires WithTLSConfig.
+func WithMaxConcurrentHandshakes(n int) Option {
+	return func(adapter *Adapter) {
+		adapter.maxHandshakes = n
+	}
+}
+
+// WithMutualTLS verifies the client certificates against the given pool, the clients having to
+// present one when required or only when they do otherwise. It requires WithTLSConfig, see
+// ClientCertificateSubject to authorize the clients.
+func WithMutualTLS(caCertPool *x509.CertPool, required bool) Option {
+	return func(adapter *Adapter) {
+		adapter.clientCAs = caCertPool
+		adapter.clientAuth = tls.VerifyClientCertIfGiven
+		if required {
+			adapter.clientAuth = tls.RequireAndVerifyClientCert
+		}
+	}
+}
+
+// WithMaxURLLength answers 414 to the requests whose URI, query string included, is longer than n
+// bytes, before they reach the router or the instrumentation so that pathological paths don't end
+// up in the metrics. The internal endpoints are not checked.
+func WithMaxURLLength(n int) Option {
+	return func(adapter *Adapter) {
+		adapter.maxURLLength = n
+	}
+}
+
+// WithGlobalDeadline bounds the context of every request by d before any middleware runs, so that
+// the handlers and the calls they make with it stop once it expires. A request whose handler wrote
+// nothing by then, or returned the context error as a HandlerFunc, is answered a 503 and labeled
+// so by the instrumentation.
+func WithGlobalDeadline(d time.Duration) Option {
+	return func(adapter *Adapter) {
+		adapter.globalDeadline = d
+	}
+}
+
+// WithMiddlewares enables the built-in middlewares of the set with their default settings, along
+// the ones of the Middlewares setting of the configuration. They are chained in their canonical
+// order, before the middlewares added by the other options whatever the order of the options.
+func WithMiddlewares(set MiddlewareSet) Option {
+	return func(adapter *Adapter) {
+		adapter.config.Middlewares |= set
+	}
+}
+
+// WithServerHeader sets the Server header of every response, internal endpoints included, to the
+// given value, overriding the one set by the handlers. An empty value strips it.
+func WithServerHeader(value string) Option {
+	return func(adapter *Adapter) {
+		adapter.serverHeader = &value
+	}
+}
+
+// WithoutAppHealthChecks only runs the checks of WithHealthCheck on the health probes, not all the
+// ones of the application the adapter is given to, e.g. for an admin adapter.
+func WithoutAppHealthChecks() Option {
+	return func(adapter *Adapter) {
+		adapter.ignoreAppHealthChecks = true
+	}
+}
+
+// WithHealthTimeout bounds the time the health checks, which run concurrently, have to return: the
+// probe answers within it, reporting the checks still running as timing out.
+func WithHealthTimeout(d time.Duration) Option {
+	return func(adapter *Adapter) {
+		adapter.healthTimeout = d
+	}
+}
+
+// WithHealthFailFast answers the probe as soon as a critical health check fails, cancelling the
+// checks still running which are reported as cancelled.
+func WithHealthFailFast() Option {
+	return func(adapter *Adapter) {
+		adapter.healthFailFast = true
+	}
+}
+
+// WithETag sets a strong ETag, hashing the body, on the successful GET and HEAD responses that don't
+// have one and answers 304 when it matches the If-None-Match header of the request. The responses
+// are buffered to be hashed: those larger than maxBytes, or flushed by the handler like StreamJSON
+// does, are streamed as they are, without ETag. HEAD requests are handled as GET ones, to hash the
+// same body.
+func WithETag(maxBytes int) Option {
+	return func(adapter *Adapter) {
+		adapter.middlewares = append(adapter.middlewares, etagMiddleware(maxBytes))
+	}
+}
+
+// WithRequestDecompression decompresses the gzip and deflate request bodies, per their
+// Content-Encoding, for the handlers to read them as is. Reading more than maxBytes decompressed
+// bytes fails with an *http.MaxBytesError, rendered as a 413 by DecodeJSON, guarding against zip
+// bombs. A malformed body is answered 400 and another encoding 415.
+func WithRequestDecompression(maxBytes int64) Option {
+	return func(adapter *Adapter) {
+		adapter.middlewares = append(adapter.middlewares, decompressionMiddleware(maxBytes))
+	}
+}
+
+// WithCustomLabel adds the label to the request metrics, its value being returned by fn for the
+// request as received by the adapter, e.g. a tenant tier from a header. The values must be
+// bounded: past 100 distinct values, the new ones are recorded as "other" and a warning is logged.
+// It panics if the name is not a valid label name or is already used.
+func WithCustomLabel(name string, fn func(*http.Request) string) Option {
+	return func(adapter *Adapter) {
+		adapter.addCustomLabel(name, fn)
+	}
+}
+
+// WithFavicon serves the icon on /favicon.ico, or a 204 if it is empty, as an internal endpoint
+// kept out of the application handler and the metrics.
+func WithFavicon(icon []byte) Option {
+	return func(adapter *Adapter) {
+		adapter.handleInternal("/favicon.ico", faviconHandler(icon))
+	}
+}
+
+// WithRobotsTxt serves the content on /robots.txt as an internal endpoint kept out of the
+// application handler and the metrics.
+func WithRobotsTxt(content string) Option {
+	return func(adapter *Adapter) {
+		adapter.handleInternal("/robots.txt", robotsTxtHandler(content))
+	}
+}
+
+// WithIdempotency makes the POST requests with an Idempotency-Key header idempotent: the response
+// is kept in the store for ttl and replayed, with an Idempotent-Replayed header, to the requests
+// of the same client with the same key on the same path, without running the handler again. The
+// clients are told apart by their credentials, see WithIdempotencyScope. Requests arriving while
+// the one with the same key is in flight on this instance get a 409, and server errors are not
+// stored so that they can be retried. A key reused with another body is answered 422.
+//
+// The request bodies are read to be compared, those larger than maxBytes being answered 413, and
+// the responses larger than maxBytes are not stored.
+func WithIdempotency(store IdempotencyStore, ttl time.Duration, maxBytes int) Option {
+	return func(adapter *Adapter) {
+		adapter.middlewares = append(adapter.middlewares, adapter.idempotencyMiddleware(store, ttl, maxBytes))
+	}
+}
+
+// WithIdempotencyScope sets how the clients, whose idempotency keys are kept apart, are told apart,
+// by a hash of their Authorization and Cookie headers, or their IP without any, by default. It
+// should return the authenticated identity, e.g. a tenant or user ID, when there is one.
+func WithIdempotencyScope(fn func(*http.Request) string) Option {
+	return func(adapter *Adapter) {
+		adapter.idempotencyScope = fn
+	}
+}
+
+// WithSLO sets the latency objective of the route, given by its path template: its requests are
+// counted in app_slo_requests_total, and those slower than threshold in app_slo_violations_total.
+// The recorder set by WithMetricsRecorder gets them if it implements SLORecorder.
+func WithSLO(route string, threshold time.Duration) Option {
+	return func(adapter *Adapter) {
+		if adapter.slos == nil {
+			adapter.slos = make(map[string]time.Duration)
+		}
+		adapter.slos[route] = threshold
+	}
+}
+
+// WithSlowRequestLog logs the method, route, status and duration of the requests taking longer
+// than threshold, timed by the request instrumentation, at the warn level unless set otherwise by
+// WithSlowRequestLogLevel.
+func WithSlowRequestLog(threshold time.Duration) Option {
+	return func(adapter *Adapter) {
+		adapter.slowRequestThreshold = threshold
+	}
+}
+
+// WithSlowRequestLogLevel sets the level of the entries logged by WithSlowRequestLog, the error
+// level at most.
+func WithSlowRequestLogLevel(level kurin.Level) Option {
+	return func(adapter *Adapter) {
+		adapter.slowRequestLevel = level
+	}
+}
+
+// WithContentTypeMetrics counts the requests by media type of their body and of their response in
+// app_content_types_total, the types other than the given ones, or a default list of the common
+// ones when none is given, being counted as "other" and a missing type as "none". The recorder set
+// by WithMetricsRecorder gets them if it implements ContentTypeRecorder.
+func WithContentTypeMetrics(types ...string) Option {
+	return func(adapter *Adapter) {
+		if len(types) == 0 {
+			types = defaultMetricsContentTypes
+		}
+		adapter.contentTypes = make(map[string]bool, len(types))
+		for _, t := range types {
+			adapter.contentTypes[strings.ToLower(t)] = true
+		}
+	}
+}
+
+// WithSerializer lets Respond answer the media type, e.g. application/msgpack, with the serializer
+// when the Accept header of the request prefers it. It replaces the JSON serializer for
+// application/json.
+func WithSerializer(mediaType string, serializer Serializer) Option {
+	return func(adapter *Adapter) {
+		if adapter.serializers == nil {
+			adapter.serializers = make(map[string]Serializer)
+		}
+		adapter.serializers[strings.ToLower(mediaType)] = serializer
+	}
+}
+
+// WithClock sets the clock timing the requests and the time dependent features, the system clock
+// by default, e.g. a kurin.MockClock in tests.
+func WithClock(clock kurin.Clock) Option {
+	return func(adapter *Adapter) {
+		adapter.clock = clock
+	}
+}
@@ -0,0 +1,58 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+type (
+	// PaginationError is returned by Pagination for an invalid parameter, it renders as a 400
+	// through WriteError.
+	PaginationError struct {
+		Param string
+		Value string
+	}
+)
+
+// Pagination parses the limit and offset query parameters of r. A missing limit defaults to
+// defaultLimit and a limit above maxLimit is lowered to it, a missing offset defaults to 0.
+// A limit which is not a positive integer, or an offset which is not a non-negative one,
+// is a *PaginationError.
+func Pagination(r *http.Request, defaultLimit int, maxLimit int) (limit int, offset int, err error) {
+	query := r.URL.Query()
+
+	limit = defaultLimit
+	if value := query.Get("limit"); value != "" {
+		if limit, err = strconv.Atoi(value); err != nil || limit < 1 {
+			return 0, 0, &PaginationError{"limit", value}
+		}
+	}
+	if limit > maxLimit {
+		limit = maxLimit
+	}
+
+	if value := query.Get("offset"); value != "" {
+		if offset, err = strconv.Atoi(value); err != nil || offset < 0 {
+			return 0, 0, &PaginationError{"offset", value}
+		}
+	}
+
+	return limit, offset, nil
+}
+
+func (err *PaginationError) Error() string {
+	if err.Param == "offset" {
+		return fmt.Sprintf("offset must be a non-negative integer, got %q", err.Value)
+	}
+
+	return fmt.Sprintf("%s must be a positive integer, got %q", err.Param, err.Value)
+}
+
+func (err *PaginationError) StatusCode() int {
+	return http.StatusBadRequest
+}
+
+func (err *PaginationError) Category() string {
+	return "invalid_pagination"
+}
@@ -0,0 +1,98 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync/atomic"
+)
+
+const (
+	readinessReady    = "ready"
+	readinessDegraded = "degraded"
+	readinessNotReady = "not_ready"
+)
+
+type readinessResponse struct {
+	Status       string                          `json:"status"`
+	Error        string                          `json:"error,omitempty"`
+	Dependencies map[string]string               `json:"dependencies,omitempty"`
+	Checks       map[string]*healthCheckResponse `json:"checks,omitempty"`
+}
+
+// liveHandler answers the process should be kept running as long as it serves requests, whatever
+// its readiness and the failures of its dependencies, which only make it not ready.
+func (adapter *Adapter) liveHandler(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// readyHandler answers whether the adapter should get traffic as JSON: it is not ready while
+// shutting down, unhealthy, warming up, set not ready, one of its dependencies is not ready or one
+// of the critical health checks fails. A failing non critical check keeps it ready but degraded.
+func (adapter *Adapter) readyHandler(w http.ResponseWriter, r *http.Request) {
+	response := &readinessResponse{Status: readinessReady}
+	if checks := adapter.checks(); len(checks) > 0 {
+		response.Checks = make(map[string]*healthCheckResponse, len(checks))
+		failing, degraded := adapter.evaluateHealthChecks(r.Context(), checks, response.Checks)
+		if failing {
+			response.Status = readinessNotReady
+		} else if degraded {
+			response.Status = readinessDegraded
+		}
+	}
+
+	adapter.mu.RLock()
+	if len(adapter.dependencies) > 0 {
+		response.Dependencies = make(map[string]string, len(adapter.dependencies))
+	}
+	for name, ready := range adapter.dependencies {
+		response.Dependencies[name] = readinessReady
+		if !ready {
+			response.Dependencies[name] = readinessNotReady
+			response.Status = readinessNotReady
+		}
+	}
+	switch {
+	case adapter.stopping:
+		response.Status, response.Error = readinessNotReady, "shutting down"
+	case !adapter.healthy:
+		response.Status = readinessNotReady
+		if adapter.lastError != nil {
+			response.Error = adapter.lastError.Error()
+		}
+	case atomic.LoadInt32(&adapter.warming) == 1:
+		response.Status, response.Error = readinessNotReady, "warming up"
+	case adapter.notReady:
+		response.Status = readinessNotReady
+	}
+	adapter.mu.RUnlock()
+
+	status := http.StatusOK
+	if response.Status == readinessNotReady {
+		status = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
+}
+
+// SetReady sets whether the adapter is ready for traffic on the readiness endpoint, e.g. not ready
+// until the application finished starting up when set right after creating the adapter. It starts
+// ready and leaves the liveness endpoint, and the health one, untouched.
+func (adapter *Adapter) SetReady(ready bool) {
+	adapter.mu.Lock()
+	defer adapter.mu.Unlock()
+
+	adapter.notReady = !ready
+}
+
+// SetDependencyReady registers the dependency, e.g. "postgres", on its first call and sets whether
+// it is ready: the adapter is not ready while one of its dependencies is not.
+func (adapter *Adapter) SetDependencyReady(name string, ready bool) {
+	adapter.mu.Lock()
+	defer adapter.mu.Unlock()
+
+	if adapter.dependencies == nil {
+		adapter.dependencies = make(map[string]bool)
+	}
+	adapter.dependencies[name] = ready
+}
@@ -0,0 +1,67 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+type (
+	// RateLimitStore holds the token buckets shared by the replicas of the application, e.g. the
+	// Redis one of the redis adapter package. Allow takes a token from the bucket of the key,
+	// refilled at rps up to burst tokens, or reports how long to wait for the next one. Taking
+	// the token must be atomic for the limit to hold across the replicas.
+	RateLimitStore interface {
+		Allow(ctx context.Context, key string, rps float64, burst int) (bool, time.Duration, error)
+	}
+)
+
+// rateLimitStoreTimeout bounds the calls to the rate limit store, so that a slow store fails open
+// instead of stalling the requests.
+const rateLimitStoreTimeout = 100 * time.Millisecond
+
+func (adapter *Adapter) rateLimitMiddleware(store RateLimitStore, rps float64, burst int) Middleware {
+	// failing is 1 while the store is unavailable, only its changes being logged.
+	var failing int32
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithTimeout(r.Context(), rateLimitStoreTimeout)
+			allowed, retryAfter, err := store.Allow(ctx, adapter.rateLimitKey(r), rps, burst)
+			cancel()
+			if err != nil {
+				// An unavailable store must not take the application down with it.
+				if atomic.CompareAndSwapInt32(&failing, 0, 1) {
+					adapter.logger.Error(fmt.Sprintf("Unable to check the rate limit, allowing the requests until the store recovers: %s", err))
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+			if atomic.CompareAndSwapInt32(&failing, 1, 0) {
+				adapter.logger.Info("Rate limit store recovered, limiting the requests again")
+			}
+			if !allowed {
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Max(1, math.Ceil(retryAfter.Seconds())))))
+				writeError(w, http.StatusTooManyRequests, "rate_limited")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// clientIP is the default rate limit key, the IP of the peer of the connection.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
@@ -0,0 +1,65 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/maxperrimond/kurin"
+)
+
+// hungStore is a rate limit store hanging until the context of the call is done while down is 1,
+// allowing every request otherwise.
+type hungStore struct {
+	down int32
+}
+
+func (store *hungStore) Allow(ctx context.Context, key string, rps float64, burst int) (bool, time.Duration, error) {
+	if atomic.LoadInt32(&store.down) == 0 {
+		return true, 0, nil
+	}
+	<-ctx.Done()
+
+	return false, 0, ctx.Err()
+}
+
+func TestRateLimitFailsOpen(t *testing.T) {
+	store := &hungStore{down: 1}
+	router := mux.NewRouter()
+	router.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	adapter := newTestAdapter(t, router, WithDistributedRateLimit(store, 1, 1))
+	var logs bytes.Buffer
+	adapter.logger = kurin.NewStdLogger(&logs, kurin.LevelInfo)
+
+	serve := func() int {
+		w := httptest.NewRecorder()
+		adapter.srv.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		return w.Code
+	}
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if code := serve(); code != http.StatusNoContent {
+			t.Fatalf("got %d, want the request allowed while the store hangs", code)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > 3*rateLimitStoreTimeout+time.Second {
+		t.Errorf("got %s for 3 requests, want the store calls bounded", elapsed)
+	}
+	atomic.StoreInt32(&store.down, 0)
+	serve()
+
+	if failures := strings.Count(logs.String(), "Unable to check the rate limit"); failures != 1 {
+		t.Errorf("got %d failures logged, want the change only:\n%s", failures, logs.String())
+	}
+	if !strings.Contains(logs.String(), "Rate limit store recovered") {
+		t.Errorf("got %q, want the recovery logged", logs.String())
+	}
+}
@@ -0,0 +1,69 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"runtime/debug"
+)
+
+// recoveryWriter tells whether the response started, for the requests served without the
+// instrumentation writer.
+type recoveryWriter struct {
+	http.ResponseWriter
+	started bool
+}
+
+func (rw *recoveryWriter) WriteHeader(code int) {
+	rw.started = true
+	rw.ResponseWriter.WriteHeader(code)
+}
+
+func (rw *recoveryWriter) Write(b []byte) (int, error) {
+	rw.started = true
+	return rw.ResponseWriter.Write(b)
+}
+
+func (rw *recoveryWriter) Flush() {
+	if f, ok := rw.ResponseWriter.(http.Flusher); ok {
+		rw.started = true
+		f.Flush()
+	}
+}
+
+// Unwrap gives http.ResponseController access to the wrapped writer.
+func (rw *recoveryWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// recover answers the panics of the wrapped handler with the recovery handler, a JSON 500 by
+// default, and logs them with their stack. A started response is aborted instead. It tells the
+// response started from the writer of the instrumentation when there is one, not to wrap it again.
+func (adapter *Adapter) recover(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var rw *recoveryWriter
+		crw, instrumented := w.(*customResponseWriter)
+		if !instrumented {
+			rw = &recoveryWriter{ResponseWriter: w}
+			w = rw
+		}
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+			if recovered == http.ErrAbortHandler {
+				panic(recovered)
+			}
+
+			adapter.logger.Error(fmt.Sprintf("Panic serving %s %s: %v\n%s", r.Method, r.URL.Path, recovered, debug.Stack()))
+			if instrumented && !crw.firstWrite.IsZero() || !instrumented && rw.started {
+				// The response cannot be replaced, the connection is aborted so that the client
+				// does not take it as complete.
+				panic(http.ErrAbortHandler)
+			}
+			adapter.recoveryHandler.ServeHTTP(w, r)
+		}()
+
+		next.ServeHTTP(w, r)
+	})
+}
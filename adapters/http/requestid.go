@@ -0,0 +1,73 @@
+package http
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+
+	"github.com/maxperrimond/kurin"
+)
+
+const (
+	requestIDHeader    = "X-Request-ID"
+	maxRequestIDLength = 128
+)
+
+func (adapter *Adapter) requestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestIDHeader)
+		if !validRequestID(id) {
+			id = adapter.requestIDGenerator()
+		}
+		w.Header().Set(requestIDHeader, id)
+
+		ctx := kurin.ContextWithRequestID(r.Context(), id)
+		if adapter.logger != nil {
+			keysAndValues := []interface{}{"request_id", id}
+			if adapter.traceID != nil {
+				if traceID := adapter.traceID(ctx); traceID != "" {
+					keysAndValues = append(keysAndValues, "trace_id", traceID)
+				}
+			}
+			ctx = kurin.ContextWithLogger(ctx, kurin.WithFields(adapter.logger, keysAndValues...))
+		}
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// validRequestID only accepts printable ASCII IDs of a reasonable length, so that
+// clients cannot forge log entries through them.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < '!' || id[i] > '~' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// NewUUID returns a random (version 4) UUID, the default request ID generator.
+func NewUUID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+
+	var s [36]byte
+	hex.Encode(s[0:8], b[0:4])
+	s[8] = '-'
+	hex.Encode(s[9:13], b[4:6])
+	s[13] = '-'
+	hex.Encode(s[14:18], b[6:8])
+	s[18] = '-'
+	hex.Encode(s[19:23], b[8:10])
+	s[23] = '-'
+	hex.Encode(s[24:], b[10:])
+
+	return string(s[:])
+}
@@ -0,0 +1,99 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const jsonMediaType = "application/json"
+
+type (
+	// Serializer marshals the payloads given to Respond into a media type, see WithSerializer.
+	Serializer interface {
+		Marshal(v interface{}) ([]byte, error)
+	}
+
+	// SerializerFunc turns a marshal function, e.g. msgpack.Marshal, into a Serializer.
+	SerializerFunc func(v interface{}) ([]byte, error)
+
+	serializersKey struct{}
+
+	jsonSerializer struct{}
+)
+
+// Respond answers payload with the status, serialized in the media type preferred by the Accept
+// header of the request among JSON and the ones registered with WithSerializer, JSON when none of
+// them is acceptable. A marshal error is returned before anything is written, to be returned by
+// a HandlerFunc which answers it as a 500.
+func Respond(w http.ResponseWriter, r *http.Request, status int, payload interface{}) error {
+	serializers, _ := r.Context().Value(serializersKey{}).(map[string]Serializer)
+	mediaType, serializer := negotiate(r.Header.Get("Accept"), serializers)
+
+	body, err := serializer.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", mediaType)
+	w.WriteHeader(status)
+	_, err = w.Write(body)
+
+	return err
+}
+
+func (fn SerializerFunc) Marshal(v interface{}) ([]byte, error) {
+	return fn(v)
+}
+
+func (jsonSerializer) Marshal(v interface{}) ([]byte, error) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(body, '\n'), nil
+}
+
+// negotiate picks the serializer of the acceptable media type with the highest quality, the first
+// listed winning ties.
+func negotiate(accept string, serializers map[string]Serializer) (string, Serializer) {
+	// The JSON serializer, that of WithSerializer if any, answers by default and the wildcards.
+	fallback := Serializer(jsonSerializer{})
+	if s, ok := serializers[jsonMediaType]; ok {
+		fallback = s
+	}
+	mediaType, serializer, best := jsonMediaType, fallback, -1.0
+	for _, value := range strings.Split(accept, ",") {
+		candidate, params, err := mime.ParseMediaType(value)
+		if err != nil {
+			continue
+		}
+		quality := 1.0
+		if q, ok := params["q"]; ok {
+			if quality, err = strconv.ParseFloat(q, 64); err != nil {
+				continue
+			}
+		}
+		if quality <= 0 || quality <= best {
+			continue
+		}
+
+		if s, ok := serializers[candidate]; ok {
+			mediaType, serializer, best = candidate, s, quality
+		} else if candidate == jsonMediaType || candidate == "application/*" || candidate == "*/*" {
+			mediaType, serializer, best = jsonMediaType, fallback, quality
+		}
+	}
+
+	return mediaType, serializer
+}
+
+func (adapter *Adapter) serializersMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), serializersKey{}, adapter.serializers)))
+	})
+}
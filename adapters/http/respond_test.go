@@ -0,0 +1,16 @@
+package http
+
+import "testing"
+
+func TestNegotiateDefaultSerializer(t *testing.T) {
+	custom := SerializerFunc(func(v interface{}) ([]byte, error) { return []byte("custom"), nil })
+	serializers := map[string]Serializer{jsonMediaType: custom}
+
+	for _, accept := range []string{"", "*/*", "application/*", "application/json", "text/html"} {
+		mediaType, serializer := negotiate(accept, serializers)
+		body, _ := serializer.Marshal(nil)
+		if mediaType != jsonMediaType || string(body) != "custom" {
+			t.Errorf("%q: got %s %q, want the serializer given for application/json", accept, mediaType, body)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package http
+
+import "net/http"
+
+type (
+	// SecurityHeadersConfig lists the values of the security headers set on every response of the
+	// wrapped handler, an empty value leaving the header unset. Strict-Transport-Security is only
+	// sent on TLS requests unless ForceHSTS is set, e.g. when TLS is terminated by a proxy.
+	SecurityHeadersConfig struct {
+		ContentTypeOptions      string
+		FrameOptions            string
+		StrictTransportSecurity string
+		ContentSecurityPolicy   string
+		ForceHSTS               bool
+	}
+)
+
+func DefaultSecurityHeadersConfig() SecurityHeadersConfig {
+	return SecurityHeadersConfig{
+		ContentTypeOptions:      "nosniff",
+		FrameOptions:            "DENY",
+		StrictTransportSecurity: "max-age=63072000; includeSubDomains",
+		ContentSecurityPolicy:   "default-src 'none'; frame-ancestors 'none'",
+	}
+}
+
+func securityHeadersMiddleware(config SecurityHeadersConfig) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			header := w.Header()
+			if config.ContentTypeOptions != "" {
+				header.Set("X-Content-Type-Options", config.ContentTypeOptions)
+			}
+			if config.FrameOptions != "" {
+				header.Set("X-Frame-Options", config.FrameOptions)
+			}
+			if config.StrictTransportSecurity != "" && (r.TLS != nil || config.ForceHSTS) {
+				header.Set("Strict-Transport-Security", config.StrictTransportSecurity)
+			}
+			if config.ContentSecurityPolicy != "" {
+				header.Set("Content-Security-Policy", config.ContentSecurityPolicy)
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
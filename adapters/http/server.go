@@ -0,0 +1,57 @@
+package http
+
+import "net/http"
+
+// serverHeaderWriter sets the Server header right before the headers are sent, overriding the one
+// the handler may have set.
+type serverHeaderWriter struct {
+	http.ResponseWriter
+	value       string
+	wroteHeader bool
+}
+
+func serverHeaderMiddleware(value string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			sw := &serverHeaderWriter{ResponseWriter: w, value: value}
+			next.ServeHTTP(sw, r)
+			// The headers of a handler writing nothing are only sent once it returns.
+			sw.setHeader()
+		})
+	}
+}
+
+func (w *serverHeaderWriter) WriteHeader(code int) {
+	w.setHeader()
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *serverHeaderWriter) Write(b []byte) (int, error) {
+	w.setHeader()
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *serverHeaderWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		w.setHeader()
+		f.Flush()
+	}
+}
+
+// Unwrap gives http.ResponseController access to the wrapped writer.
+func (w *serverHeaderWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *serverHeaderWriter) setHeader() {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+
+	if w.value == "" {
+		w.Header().Del("Server")
+	} else {
+		w.Header().Set("Server", w.value)
+	}
+}
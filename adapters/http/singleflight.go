@@ -0,0 +1,149 @@
+package http
+
+import (
+	"net/http"
+
+	"golang.org/x/sync/singleflight"
+)
+
+type (
+	sharedResponse struct {
+		status  int
+		header  http.Header
+		trailer http.Header
+		body    []byte
+		// unshared is set when the response was only written for the leader, being too large,
+		// flushed, or produced for a cancelled request.
+		unshared bool
+	}
+
+	// bufferingWriter buffers the response, to share it or compute its ETag, until it exceeds
+	// the size cap or the handler flushes it, e.g. to stream it, and is then written through.
+	bufferingWriter struct {
+		w           http.ResponseWriter
+		header      http.Header
+		status      int
+		body        []byte
+		maxBytes    int
+		passthrough bool
+	}
+)
+
+func singleflightMiddleware(maxBytes int) Middleware {
+	var group singleflight.Group
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			leader := false
+			// The response depends on the credentials and on the negotiated media type and encoding.
+			key := r.Method + " " + r.URL.RequestURI()
+			for _, header := range []string{"Authorization", "Cookie", "Accept", "Accept-Encoding"} {
+				key += "\x00" + r.Header.Get(header)
+			}
+			v, _, _ := group.Do(key, func() (interface{}, error) {
+				leader = true
+				bw := &bufferingWriter{w: w, header: http.Header{}, status: http.StatusOK, maxBytes: maxBytes}
+				next.ServeHTTP(bw, r)
+				if bw.passthrough {
+					return &sharedResponse{unshared: true}, nil
+				}
+				// The handler of a cancelled request may have answered anything, or nothing.
+				if r.Context().Err() != nil {
+					bw.writeThrough()
+					return &sharedResponse{unshared: true}, nil
+				}
+
+				trailer := splitTrailers(bw.header)
+
+				return &sharedResponse{status: bw.status, header: bw.header, trailer: trailer, body: bw.body}, nil
+			})
+			response := v.(*sharedResponse)
+
+			switch {
+			case response.unshared && leader:
+			case response.unshared:
+				next.ServeHTTP(w, r)
+			default:
+				for key, values := range response.header {
+					w.Header()[key] = append([]string(nil), values...)
+				}
+				w.WriteHeader(response.status)
+				w.Write(response.body)
+				writeTrailers(w, response.trailer)
+			}
+		})
+	}
+}
+
+func (bw *bufferingWriter) Header() http.Header {
+	if bw.passthrough {
+		return bw.w.Header()
+	}
+
+	return bw.header
+}
+
+func (bw *bufferingWriter) WriteHeader(code int) {
+	if bw.passthrough {
+		bw.w.WriteHeader(code)
+		return
+	}
+	bw.status = code
+}
+
+func (bw *bufferingWriter) Write(b []byte) (int, error) {
+	if bw.passthrough {
+		return bw.w.Write(b)
+	}
+	if len(bw.body)+len(b) <= bw.maxBytes {
+		bw.body = append(bw.body, b...)
+		return len(b), nil
+	}
+
+	if err := bw.writeThrough(); err != nil {
+		return 0, err
+	}
+
+	return bw.w.Write(b)
+}
+
+// Flush gives up buffering, writing the response buffered so far before flushing it.
+func (bw *bufferingWriter) Flush() {
+	f, ok := bw.w.(http.Flusher)
+	if !ok {
+		return
+	}
+	if !bw.passthrough {
+		if err := bw.writeThrough(); err != nil {
+			return
+		}
+	}
+	f.Flush()
+}
+
+// Unwrap gives http.ResponseController access to the wrapped writer.
+func (bw *bufferingWriter) Unwrap() http.ResponseWriter {
+	return bw.w
+}
+
+// writeThrough writes the response buffered so far, the following writes going through.
+func (bw *bufferingWriter) writeThrough() error {
+	bw.passthrough = true
+	for key, values := range bw.header {
+		bw.w.Header()[key] = values
+	}
+	bw.w.WriteHeader(bw.status)
+	body := bw.body
+	bw.body = nil
+	if len(body) == 0 {
+		return nil
+	}
+	_, err := bw.w.Write(body)
+
+	return err
+}
@@ -0,0 +1,80 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+func TestSingleflightKeyedByNegotiation(t *testing.T) {
+	var calls int32
+	release := make(chan struct{})
+	router := mux.NewRouter()
+	router.HandleFunc("/report", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		w.Write([]byte(r.Header.Get("Accept")))
+	})
+	adapter := newTestAdapter(t, router, WithSingleflight(1024))
+
+	bodies := make(chan string, 2)
+	for _, accept := range []string{"application/json", "application/msgpack"} {
+		go func(accept string) {
+			r := httptest.NewRequest(http.MethodGet, "/report", nil)
+			r.Header.Set("Accept", accept)
+			w := httptest.NewRecorder()
+			adapter.srv.Handler.ServeHTTP(w, r)
+			bodies <- w.Body.String()
+		}(accept)
+	}
+	for start := time.Now(); atomic.LoadInt32(&calls) < 2; time.Sleep(time.Millisecond) {
+		if time.Since(start) > time.Second {
+			t.Fatal("got the requests of different Accept headers sharing one execution")
+		}
+	}
+	close(release)
+
+	got := map[string]bool{<-bodies: true, <-bodies: true}
+	if !got["application/json"] || !got["application/msgpack"] {
+		t.Errorf("got bodies %v, want one per media type", got)
+	}
+}
+
+func TestSingleflightCancelledLeader(t *testing.T) {
+	var calls int32
+	entered := make(chan struct{})
+	router := mux.NewRouter()
+	router.HandleFunc("/report", func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			close(entered)
+			<-r.Context().Done()
+			w.Write([]byte("partial"))
+			return
+		}
+		w.Write([]byte("full"))
+	})
+	adapter := newTestAdapter(t, router, WithSingleflight(1024))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go adapter.srv.Handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/report", nil).WithContext(ctx))
+	<-entered
+
+	follower := make(chan string)
+	go func() {
+		w := httptest.NewRecorder()
+		adapter.srv.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/report", nil))
+		follower <- w.Body.String()
+	}()
+	// Leaves the follower the time to wait for the leader.
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+
+	if body := <-follower; body != "full" {
+		t.Errorf("got %q, want the follower served by its own execution", body)
+	}
+}
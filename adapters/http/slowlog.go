@@ -0,0 +1,34 @@
+package http
+
+import (
+	"time"
+
+	"github.com/maxperrimond/kurin"
+)
+
+// logSlowRequest logs the request at the level set by WithSlowRequestLogLevel when it took longer
+// than the threshold set by WithSlowRequestLog.
+func (adapter *Adapter) logSlowRequest(labels RequestLabels, duration time.Duration) {
+	if adapter.slowRequestThreshold <= 0 || duration <= adapter.slowRequestThreshold {
+		return
+	}
+
+	logger := kurin.WithFields(adapter.logger,
+		"method", labels.Method,
+		"route", labels.Handler,
+		"status", labels.Code,
+		"duration", duration,
+	)
+	msg := "Slow request"
+	switch adapter.slowRequestLevel {
+	case kurin.LevelDebug:
+		logger.Debug(msg)
+	case kurin.LevelInfo:
+		logger.Info(msg)
+	case kurin.LevelError, kurin.LevelFatal, kurin.LevelPanic:
+		// A slow request is never worth stopping the process.
+		logger.Error(msg)
+	default:
+		logger.Warn(msg)
+	}
+}
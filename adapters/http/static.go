@@ -0,0 +1,29 @@
+package http
+
+import "net/http"
+
+func (adapter *Adapter) handleInternal(path string, h http.Handler) {
+	if adapter.internalHandlers == nil {
+		adapter.internalHandlers = make(map[string]http.Handler)
+	}
+	adapter.internalHandlers[path] = h
+}
+
+func faviconHandler(icon []byte) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if len(icon) == 0 {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.Header().Set("Content-Type", "image/x-icon")
+		w.Header().Set("Cache-Control", "public, max-age=86400")
+		w.Write(icon)
+	}
+}
+
+func robotsTxtHandler(content string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Write([]byte(content))
+	}
+}
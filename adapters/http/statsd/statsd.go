@@ -0,0 +1,109 @@
+package statsd
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	httpAdapter "github.com/maxperrimond/kurin/adapters/http"
+)
+
+type (
+	// Recorder sends the HTTP adapter metrics over UDP using the DogStatsD format,
+	// labels being sent as tags.
+	Recorder struct {
+		conn   net.Conn
+		prefix string
+	}
+)
+
+var tagReplacer = strings.NewReplacer("|", "_", ",", "_", "#", "_", "\n", "_")
+
+func NewRecorder(addr string, prefix string) (*Recorder, error) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Recorder{conn, prefix}, nil
+}
+
+func (recorder *Recorder) IncRequest(labels httpAdapter.RequestLabels) {
+	recorder.send(fmt.Sprintf("%srequests_total:1|c|%s", recorder.prefix, tags(labels)))
+}
+
+func (recorder *Recorder) ObserveDuration(labels httpAdapter.RequestLabels, d time.Duration) {
+	ms := float64(d) / float64(time.Millisecond)
+	recorder.send(fmt.Sprintf("%sresponse_duration:%g|ms|%s", recorder.prefix, ms, tags(labels)))
+}
+
+func (recorder *Recorder) ObserveResponseSize(labels httpAdapter.RequestLabels, bytes int) {
+	recorder.send(fmt.Sprintf("%sresponse_size:%d|h|%s", recorder.prefix, bytes, tags(labels)))
+}
+
+func (recorder *Recorder) ObserveTimeToFirstByte(labels httpAdapter.RequestLabels, d time.Duration) {
+	ms := float64(d) / float64(time.Millisecond)
+	recorder.send(fmt.Sprintf("%sresponse_ttfb:%g|ms|%s", recorder.prefix, ms, tags(labels)))
+}
+
+func (recorder *Recorder) IncSLORequest(labels httpAdapter.RequestLabels, violation bool) {
+	handler := tagReplacer.Replace(labels.Handler)
+	recorder.send(fmt.Sprintf("%sslo_requests_total:1|c|#handler:%s", recorder.prefix, handler))
+	if violation {
+		recorder.send(fmt.Sprintf("%sslo_violations_total:1|c|#handler:%s", recorder.prefix, handler))
+	}
+}
+
+func (recorder *Recorder) IncHandlerError(labels httpAdapter.RequestLabels, kind string) {
+	recorder.send(fmt.Sprintf(
+		"%shandler_errors_total:1|c|#handler:%s,kind:%s",
+		recorder.prefix,
+		tagReplacer.Replace(labels.Handler),
+		tagReplacer.Replace(kind),
+	))
+}
+
+func (recorder *Recorder) IncWriteError(labels httpAdapter.RequestLabels) {
+	recorder.send(fmt.Sprintf("%sresponse_write_errors_total:1|c|%s", recorder.prefix, tags(labels)))
+}
+
+func (recorder *Recorder) IncContentTypes(labels httpAdapter.RequestLabels, requestType string, responseType string) {
+	recorder.send(fmt.Sprintf(
+		"%scontent_types_total:1|c|#handler:%s,request_type:%s,response_type:%s",
+		recorder.prefix,
+		tagReplacer.Replace(labels.Handler),
+		tagReplacer.Replace(requestType),
+		tagReplacer.Replace(responseType),
+	))
+}
+
+func (recorder *Recorder) Close() error {
+	return recorder.conn.Close()
+}
+
+func (recorder *Recorder) send(packet string) {
+	// UDP is fire and forget, a lost packet must never slow down the request.
+	recorder.conn.Write([]byte(packet))
+}
+
+func tags(labels httpAdapter.RequestLabels) string {
+	tags := fmt.Sprintf(
+		"#code:%s,method:%s,handler:%s",
+		tagReplacer.Replace(labels.Code),
+		tagReplacer.Replace(labels.Method),
+		tagReplacer.Replace(labels.Handler),
+	)
+
+	names := make([]string, 0, len(labels.Custom))
+	for name := range labels.Custom {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		tags += fmt.Sprintf(",%s:%s", tagReplacer.Replace(name), tagReplacer.Replace(labels.Custom[name]))
+	}
+
+	return tags
+}
@@ -0,0 +1,78 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+const (
+	streamFlushItems    = 100
+	streamFlushInterval = 200 * time.Millisecond
+)
+
+type streamingKey struct{}
+
+// StreamingContext returns a context for long-lived handlers (SSE, streaming) that is cancelled
+// either when the request ends or as soon as the adapter begins to shut down, so that such
+// handlers don't hold the graceful shutdown until its deadline.
+func StreamingContext(r *http.Request) context.Context {
+	shutdown, ok := r.Context().Value(streamingKey{}).(chan struct{})
+	if !ok {
+		return r.Context()
+	}
+
+	ctx, cancel := context.WithCancel(r.Context())
+	go func() {
+		select {
+		case <-shutdown:
+		case <-ctx.Done():
+		}
+		cancel()
+	}()
+
+	return ctx
+}
+
+// StreamJSON writes the items received as newline-delimited JSON until the channel is closed,
+// flushing them every 100 items or 200ms. It stops early when the request ends or the adapter
+// shuts down, see StreamingContext, returning the context error: the producer should watch the
+// request context as well to stop producing.
+func StreamJSON(w http.ResponseWriter, r *http.Request, items <-chan interface{}) error {
+	ctx := StreamingContext(r)
+	flusher, _ := w.(http.Flusher)
+	encoder := json.NewEncoder(w)
+	w.Header().Set("Content-Type", "application/x-ndjson")
+
+	ticker := time.NewTicker(streamFlushInterval)
+	defer ticker.Stop()
+
+	pending := 0
+	flush := func() {
+		if flusher != nil && pending > 0 {
+			flusher.Flush()
+		}
+		pending = 0
+	}
+
+	for {
+		select {
+		case item, ok := <-items:
+			if !ok {
+				flush()
+				return nil
+			}
+			if err := encoder.Encode(item); err != nil {
+				return err
+			}
+			if pending++; pending >= streamFlushItems {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
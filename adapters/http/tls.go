@@ -0,0 +1,16 @@
+package http
+
+import (
+	"crypto/x509/pkix"
+	"net/http"
+)
+
+// ClientCertificateSubject returns the subject of the client certificate verified on the TLS
+// handshake, see WithMutualTLS, and whether the client presented one.
+func ClientCertificateSubject(r *http.Request) (pkix.Name, bool) {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return pkix.Name{}, false
+	}
+
+	return r.TLS.VerifiedChains[0][0].Subject, true
+}
@@ -0,0 +1,45 @@
+package http
+
+import (
+	"net/http"
+	"strings"
+)
+
+// trailers returns a copy of the trailers found in a response header: the fields declared by its
+// Trailer header and those prefixed with http.TrailerPrefix.
+func trailers(header http.Header) http.Header {
+	trailers := http.Header{}
+	for _, declared := range header.Values("Trailer") {
+		for _, key := range strings.Split(declared, ",") {
+			key = http.CanonicalHeaderKey(strings.TrimSpace(key))
+			if values, ok := header[key]; ok {
+				trailers[key] = append([]string(nil), values...)
+			}
+		}
+	}
+	for key, values := range header {
+		if strings.HasPrefix(key, http.TrailerPrefix) {
+			trailers[key] = append([]string(nil), values...)
+		}
+	}
+
+	return trailers
+}
+
+// splitTrailers removes the trailers from a buffered response header, so that they are not sent as
+// headers when it is written, and returns them to be set with writeTrailers.
+func splitTrailers(header http.Header) http.Header {
+	trailers := trailers(header)
+	for key := range trailers {
+		delete(header, key)
+	}
+
+	return trailers
+}
+
+// writeTrailers sets the trailers once the header and body of the response are written.
+func writeTrailers(w http.ResponseWriter, trailers http.Header) {
+	for key, values := range trailers {
+		w.Header()[key] = append([]string(nil), values...)
+	}
+}
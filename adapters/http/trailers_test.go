@@ -0,0 +1,62 @@
+package http
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+func TestTrailersPropagation(t *testing.T) {
+	tests := []struct {
+		name     string
+		method   string
+		requests int
+		options  []Option
+	}{
+		{"instrumentation", http.MethodGet, 1, nil},
+		{"etag", http.MethodGet, 1, []Option{WithETag(1024)}},
+		{"singleflight", http.MethodGet, 1, []Option{WithSingleflight(1024)}},
+		{"idempotency", http.MethodPost, 2, []Option{WithIdempotency(NewMemoryIdempotencyStore(), time.Minute, 1024)}},
+	}
+
+	for _, test := range tests {
+		router := mux.NewRouter()
+		router.HandleFunc("/stream", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Trailer", "Grpc-Status")
+			w.Write([]byte("message"))
+			w.Header().Set("Grpc-Status", "0")
+			w.Header().Set(http.TrailerPrefix+"Grpc-Message", "ok")
+		})
+		srv := httptest.NewServer(newTestAdapter(t, router, test.options...).srv.Handler)
+
+		// The second idempotent request is replayed from the store.
+		for i := 0; i < test.requests; i++ {
+			req, _ := http.NewRequest(test.method, srv.URL+"/stream", nil)
+			req.Header.Set("Idempotency-Key", "key")
+			resp, err := srv.Client().Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+
+			if i > 0 && resp.Header.Get(idempotencyReplayedHeader) != "true" {
+				t.Errorf("%s: got the request served again, want it replayed", test.name)
+			}
+			if string(body) != "message" {
+				t.Errorf("%s: got body %q, want message", test.name, body)
+			}
+			if status := resp.Header.Get("Grpc-Status"); status != "" {
+				t.Errorf("%s: got the Grpc-Status trailer %q in the header", test.name, status)
+			}
+			if status, message := resp.Trailer.Get("Grpc-Status"), resp.Trailer.Get("Grpc-Message"); status != "0" || message != "ok" {
+				t.Errorf("%s: got trailers %v, want the declared and prefixed ones", test.name, resp.Trailer)
+			}
+		}
+		srv.Close()
+	}
+}
@@ -0,0 +1,88 @@
+package http
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/maxperrimond/kurin"
+)
+
+type bodyDoneReader struct {
+	io.ReadCloser
+	once sync.Once
+	done func()
+}
+
+// UploadTimeouts lets a route receive its request body for up to bodyTimeout, overriding the
+// server read timeout, and then bounds the handler to handlerTimeout once the body is fully read
+// or closed: the request context is cancelled and the response must be written by then. It is
+// meant for the routes receiving large uploads, e.g. through Handle.
+func UploadTimeouts(bodyTimeout time.Duration, handlerTimeout time.Duration) Middleware {
+	return UploadTimeoutsWithClock(kurin.SystemClock, bodyTimeout, handlerTimeout)
+}
+
+// UploadTimeoutsWithClock is like UploadTimeouts, the handler timeout being timed by the clock. The
+// deadlines of the connection stay on the system time.
+func UploadTimeoutsWithClock(clock kurin.Clock, bodyTimeout time.Duration, handlerTimeout time.Duration) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			controller := http.NewResponseController(w)
+			now := time.Now()
+			// Errors only mean the deadlines cannot be changed, leaving the server timeouts.
+			controller.SetReadDeadline(now.Add(bodyTimeout))
+			controller.SetWriteDeadline(now.Add(bodyTimeout + handlerTimeout))
+
+			ctx, cancel := context.WithCancel(r.Context())
+			defer cancel()
+			var (
+				mu       sync.Mutex
+				finished bool
+			)
+			// stopped stops the handler timeout once the handler returned.
+			stopped := make(chan struct{})
+			defer func() {
+				mu.Lock()
+				finished = true
+				close(stopped)
+				mu.Unlock()
+			}()
+
+			r.Body = &bodyDoneReader{ReadCloser: r.Body, done: func() {
+				mu.Lock()
+				defer mu.Unlock()
+				if finished {
+					return
+				}
+				controller.SetWriteDeadline(time.Now().Add(handlerTimeout))
+				timeout := clock.After(handlerTimeout)
+				go func() {
+					select {
+					case <-timeout:
+						cancel()
+					case <-stopped:
+					}
+				}()
+			}}
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+func (body *bodyDoneReader) Read(p []byte) (int, error) {
+	n, err := body.ReadCloser.Read(p)
+	if err == io.EOF {
+		body.once.Do(body.done)
+	}
+
+	return n, err
+}
+
+func (body *bodyDoneReader) Close() error {
+	body.once.Do(body.done)
+
+	return body.ReadCloser.Close()
+}
@@ -0,0 +1,37 @@
+package http
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/maxperrimond/kurin"
+)
+
+func TestUploadHandlerTimeout(t *testing.T) {
+	clock := kurin.NewMockClock(time.Now())
+	handler := UploadTimeoutsWithClock(clock, time.Hour, time.Minute)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := io.ReadAll(r.Body); err != nil {
+			t.Error(err)
+		}
+		if r.Context().Err() != nil {
+			t.Error("got the context cancelled before the handler timeout")
+		}
+		clock.Add(time.Minute)
+		select {
+		case <-r.Context().Done():
+			w.WriteHeader(http.StatusGatewayTimeout)
+		case <-time.After(time.Second):
+			t.Error("got the context left open, want it cancelled once the handler timeout passed")
+		}
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader("file")))
+	if w.Code != http.StatusGatewayTimeout {
+		t.Errorf("got %d, want 504", w.Code)
+	}
+}
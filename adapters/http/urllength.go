@@ -0,0 +1,15 @@
+package http
+
+import "net/http"
+
+func maxURLLengthMiddleware(n int) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if len(r.RequestURI) > n {
+				writeError(w, http.StatusRequestURITooLong, "uri_too_long")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"time"
+)
+
+type (
+	// BuildInfo describes the running binary.
+	BuildInfo struct {
+		Version   string
+		Commit    string
+		GoVersion string
+	}
+
+	versionResponse struct {
+		Version       string     `json:"version"`
+		GoVersion     string     `json:"go_version"`
+		StartTime     *time.Time `json:"start_time,omitempty"`
+		UptimeSeconds float64    `json:"uptime_seconds"`
+	}
+)
+
+func (adapter *Adapter) versionHandler(w http.ResponseWriter, r *http.Request) {
+	response := &versionResponse{
+		Version:   adapter.config.Version,
+		GoVersion: runtime.Version(),
+	}
+
+	adapter.mu.RLock()
+	if !adapter.startTime.IsZero() {
+		startTime := adapter.startTime
+		response.StartTime = &startTime
+		response.UptimeSeconds = adapter.clock.Now().Sub(startTime).Seconds()
+	}
+	adapter.mu.RUnlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// ReadBuildInfo returns the build information of the binary with the given version, the commit
+// being the VCS revision embedded by the Go toolchain when built from a repository.
+func ReadBuildInfo(version string) BuildInfo {
+	info := BuildInfo{Version: version, GoVersion: runtime.Version()}
+	if build, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range build.Settings {
+			if setting.Key == "vcs.revision" {
+				info.Commit = setting.Value
+			}
+		}
+	}
+
+	return info
+}
@@ -0,0 +1,32 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+func (adapter *Adapter) warmupMiddleware(retryAfter time.Duration) Middleware {
+	seconds := int(retryAfter.Seconds())
+	if seconds < 1 {
+		seconds = 1
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if atomic.LoadInt32(&adapter.warming) == 1 {
+				w.Header().Set("Retry-After", strconv.Itoa(seconds))
+				writeError(w, http.StatusServiceUnavailable, "warming_up")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// MarkWarmedUp ends the warmup started by WithWarmup, the application requests being handled from then.
+func (adapter *Adapter) MarkWarmedUp() {
+	atomic.StoreInt32(&adapter.warming, 0)
+}
@@ -0,0 +1,134 @@
+package pushgateway
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/maxperrimond/kurin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/push"
+)
+
+type (
+	// Adapter periodically pushes the metrics of a gatherer to a Prometheus Pushgateway, for
+	// workloads that don't live long enough to be scraped, and pushes them a last time on Close.
+	// The application closes its systems in the order they were given, so give this adapter
+	// after the ones producing the metrics for the last push to include their final values.
+	Adapter struct {
+		pusher   *push.Pusher
+		url      string
+		interval time.Duration
+		logger   kurin.Logger
+		stop     chan struct{}
+		stopped  bool
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+	}
+)
+
+const (
+	maxAttempts    = 5
+	initialBackoff = time.Second
+	closeTimeout   = 10 * time.Second
+)
+
+func NewPushgatewayAdapter(url string, jobName string, interval time.Duration, gatherer prometheus.Gatherer, logger kurin.Logger) kurin.Adapter {
+	return &Adapter{
+		pusher:   push.New(url, jobName).Gatherer(gatherer),
+		url:      url,
+		interval: interval,
+		logger:   logger,
+		stop:     make(chan struct{}),
+	}
+}
+
+func (adapter *Adapter) Open() {
+	adapter.mu.Lock()
+	if adapter.stopped {
+		adapter.mu.Unlock()
+		return
+	}
+	adapter.wg.Add(1)
+	adapter.mu.Unlock()
+	defer adapter.wg.Done()
+
+	adapter.logger.Info(fmt.Sprintf("Pushing metrics to %s every %s", adapter.url, adapter.interval))
+
+	ticker := time.NewTicker(adapter.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			adapter.pushWithRetry()
+		case <-adapter.stop:
+			return
+		}
+	}
+}
+
+func (adapter *Adapter) pushWithRetry() {
+	backoff := initialBackoff
+	for attempt := 1; ; attempt++ {
+		err := adapter.pusher.Push()
+		if err == nil {
+			return
+		}
+
+		adapter.logger.Error(fmt.Sprintf("unable to push metrics (attempt %d/%d): %s", attempt, maxAttempts, err))
+		if attempt == maxAttempts {
+			return
+		}
+
+		select {
+		case <-time.After(backoff):
+			backoff *= 2
+		case <-adapter.stop:
+			return
+		}
+	}
+}
+
+func (adapter *Adapter) Close() {
+	if err := adapter.CloseContext(context.Background()); err != nil {
+		adapter.logger.Error(err)
+	}
+}
+
+// CloseContext stops the periodic pushes, waiting for the one in flight if any, and synchronously
+// pushes the metrics a last time, giving up when ctx is done or after 10 seconds.
+func (adapter *Adapter) CloseContext(ctx context.Context) error {
+	adapter.mu.Lock()
+	if !adapter.stopped {
+		adapter.stopped = true
+		close(adapter.stop)
+	}
+	adapter.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, closeTimeout)
+	defer cancel()
+
+	// A periodic push in flight is waited for, as long as ctx allows.
+	pushed := make(chan struct{})
+	go func() {
+		adapter.wg.Wait()
+		close(pushed)
+	}()
+	select {
+	case <-pushed:
+	case <-ctx.Done():
+		return fmt.Errorf("unable to push metrics on close: %s", ctx.Err())
+	}
+
+	if err := adapter.pusher.PushContext(ctx); err != nil {
+		return fmt.Errorf("unable to push metrics on close: %s", err)
+	}
+	adapter.logger.Info(fmt.Sprintf("Pushed metrics to %s on close", adapter.url))
+
+	return nil
+}
+
+func (adapter *Adapter) OnFailure(err error) {
+}
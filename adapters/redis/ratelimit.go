@@ -0,0 +1,75 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+// tokenBucket refills the bucket for the time elapsed since it was last seen, by Redis' clock so
+// that the replicas agree on it, and takes a token atomically. It returns whether a token was
+// taken, or else the seconds until the next one as a string, Lua numbers being truncated to
+// integers in the replies.
+var tokenBucket = redis.NewScript(`
+redis.replicate_commands()
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local time = redis.call("TIME")
+local now = tonumber(time[1]) + tonumber(time[2]) / 1000000
+
+local bucket = redis.call("HMGET", KEYS[1], "tokens", "ts")
+local tokens = tonumber(bucket[1]) or burst
+local ts = tonumber(bucket[2]) or now
+tokens = math.min(burst, tokens + math.max(0, now - ts) * rate)
+
+local allowed = 0
+local wait = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+else
+	wait = (1 - tokens) / rate
+end
+
+redis.call("HMSET", KEYS[1], "tokens", tokens, "ts", now)
+redis.call("PEXPIRE", KEYS[1], math.ceil(burst / rate * 1000) + 1000)
+
+return {allowed, tostring(wait)}
+`)
+
+type (
+	// RateLimitStore keeps token buckets in Redis for the distributed rate limit of the http
+	// adapter, see WithDistributedRateLimit.
+	RateLimitStore struct {
+		client *redis.Client
+		prefix string
+	}
+)
+
+// NewRateLimitStore keeps the buckets under keys starting with the prefix, e.g. "ratelimit:".
+func NewRateLimitStore(client *redis.Client, prefix string) *RateLimitStore {
+	return &RateLimitStore{client: client, prefix: prefix}
+}
+
+func (store *RateLimitStore) Allow(ctx context.Context, key string, rps float64, burst int) (bool, time.Duration, error) {
+	result, err := tokenBucket.Run(store.client.WithContext(ctx), []string{store.prefix + key}, rps, burst).Result()
+	if err != nil {
+		return false, 0, err
+	}
+
+	reply, ok := result.([]interface{})
+	if !ok || len(reply) != 2 {
+		return false, 0, fmt.Errorf("unexpected token bucket reply %v", result)
+	}
+	allowed, _ := reply[0].(int64)
+	wait, _ := reply[1].(string)
+	seconds, err := strconv.ParseFloat(wait, 64)
+	if err != nil {
+		return false, 0, fmt.Errorf("unexpected token bucket wait %q", wait)
+	}
+
+	return allowed == 1, time.Duration(seconds * float64(time.Second)), nil
+}
@@ -0,0 +1,138 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis"
+	"github.com/maxperrimond/kurin"
+)
+
+type (
+	// Adapter ties a Redis client to the application lifecycle: it pings Redis while open,
+	// reporting failures, wrapping kurin.ErrDependency, and recoveries to the application, and
+	// closes the client on Close. The ping is also a critical health check of the application,
+	// see kurin.HealthChecker.
+	Adapter struct {
+		client       *redis.Client
+		logger       kurin.Logger
+		pingInterval time.Duration
+		checkName    string
+		fail         kurin.FailNotifier
+		healthy      bool
+		mu           sync.Mutex
+		stop         chan struct{}
+		stopOnce     sync.Once
+	}
+
+	Option func(*Adapter)
+)
+
+func NewRedisAdapter(client *redis.Client, logger kurin.Logger, options ...Option) kurin.Adapter {
+	adapter := &Adapter{
+		client:       client,
+		logger:       logger,
+		pingInterval: 5 * time.Second,
+		checkName:    "redis",
+		healthy:      true,
+		stop:         make(chan struct{}),
+	}
+
+	for _, option := range options {
+		option(adapter)
+	}
+
+	return adapter
+}
+
+func WithPingInterval(d time.Duration) Option {
+	return func(adapter *Adapter) {
+		adapter.pingInterval = d
+	}
+}
+
+// WithHealthCheckName sets the name of the health check pinging Redis, "redis" by default, to tell
+// apart several adapters of an application.
+func WithHealthCheckName(name string) Option {
+	return func(adapter *Adapter) {
+		adapter.checkName = name
+	}
+}
+
+// HealthChecks returns the ping of Redis, registered as a critical check of the application.
+func (adapter *Adapter) HealthChecks() []kurin.HealthCheck {
+	return []kurin.HealthCheck{{Name: adapter.checkName, Critical: true, Check: adapter.pingContext}}
+}
+
+func (adapter *Adapter) Open() {
+	adapter.logger.Info(fmt.Sprintf("Checking redis every %s...", adapter.pingInterval))
+	adapter.ping()
+
+	ticker := time.NewTicker(adapter.pingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			adapter.ping()
+		case <-adapter.stop:
+			return
+		}
+	}
+}
+
+// pingContext pings Redis until ctx is done, the client not always honouring its context.
+func (adapter *Adapter) pingContext(ctx context.Context) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- adapter.client.WithContext(ctx).Ping().Err()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (adapter *Adapter) ping() {
+	ctx, cancel := context.WithTimeout(context.Background(), adapter.pingInterval)
+	defer cancel()
+	err := adapter.pingContext(ctx)
+
+	adapter.mu.Lock()
+	defer adapter.mu.Unlock()
+
+	if (err == nil) == adapter.healthy {
+		return
+	}
+	adapter.healthy = err == nil
+
+	if err != nil {
+		adapter.logger.Error(fmt.Sprintf("health check to redis failed: %s", err))
+		err = fmt.Errorf("%w: redis: %w", kurin.ErrDependency, err)
+	} else {
+		adapter.logger.Info("redis is reachable again")
+	}
+	adapter.fail.Fail(err)
+}
+
+func (adapter *Adapter) Close() {
+	adapter.stopOnce.Do(func() {
+		close(adapter.stop)
+	})
+
+	if err := adapter.client.Close(); err != nil {
+		adapter.logger.Error(fmt.Sprintf("unable to close redis connection properly: %s", err))
+	}
+}
+
+func (adapter *Adapter) NotifyFail(c chan error) {
+	adapter.fail.NotifyFail(c)
+}
+
+func (adapter *Adapter) OnFailure(err error) {
+}
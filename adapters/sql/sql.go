@@ -0,0 +1,179 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/maxperrimond/kurin"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type (
+	// Adapter ties a database/sql pool to the application lifecycle: while open, it pings the
+	// database, reporting failures, wrapping kurin.ErrDependency, and recoveries to the
+	// application, and publishes the pool statistics as Prometheus metrics. The pool is closed on
+	// Close. The ping is also a critical health check of the application, see kurin.HealthChecker.
+	Adapter struct {
+		db         *sql.DB
+		logger     kurin.Logger
+		interval   time.Duration
+		checkName  string
+		registerer prometheus.Registerer
+		fail       kurin.FailNotifier
+		healthy    bool
+		mu         sync.Mutex
+		stop       chan struct{}
+		stopOnce   sync.Once
+
+		openConnections prometheus.Gauge
+		connections     *prometheus.GaugeVec
+		waitCount       prometheus.Gauge
+		waitDuration    prometheus.Gauge
+		maxOpen         prometheus.Gauge
+	}
+
+	Option func(*Adapter)
+)
+
+func NewSQLAdapter(db *sql.DB, logger kurin.Logger, options ...Option) kurin.Adapter {
+	adapter := &Adapter{
+		db:         db,
+		logger:     logger,
+		interval:   5 * time.Second,
+		checkName:  "sql",
+		registerer: prometheus.DefaultRegisterer,
+		healthy:    true,
+		stop:       make(chan struct{}),
+		openConnections: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "app_sql_open_connections",
+			Help: "Number of established connections of the pool, in use or idle.",
+		}),
+		connections: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "app_sql_connections",
+			Help: "Number of connections of the pool, by state (in_use, idle).",
+		}, []string{"state"}),
+		waitCount: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "app_sql_wait_count",
+			Help: "Total number of connections waited for.",
+		}),
+		waitDuration: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "app_sql_wait_duration_seconds",
+			Help: "Total time blocked waiting for a new connection.",
+		}),
+		maxOpen: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "app_sql_max_open_connections",
+			Help: "Maximum number of open connections of the pool.",
+		}),
+	}
+
+	for _, option := range options {
+		option(adapter)
+	}
+
+	for _, collector := range []prometheus.Collector{adapter.openConnections, adapter.connections, adapter.waitCount, adapter.waitDuration, adapter.maxOpen} {
+		adapter.register(collector)
+	}
+
+	return adapter
+}
+
+// WithInterval sets how often the database is pinged and the pool statistics published, every 5s by default.
+func WithInterval(d time.Duration) Option {
+	return func(adapter *Adapter) {
+		adapter.interval = d
+	}
+}
+
+// WithRegisterer sets where the pool statistics are registered, prometheus.DefaultRegisterer by
+// default. Give every adapter of an application its own, the metrics being the same.
+func WithRegisterer(registerer prometheus.Registerer) Option {
+	return func(adapter *Adapter) {
+		adapter.registerer = registerer
+	}
+}
+
+// WithHealthCheckName sets the name of the health check pinging the database, "sql" by default,
+// to tell apart several adapters of an application.
+func WithHealthCheckName(name string) Option {
+	return func(adapter *Adapter) {
+		adapter.checkName = name
+	}
+}
+
+// HealthChecks returns the ping of the database, registered as a critical check of the application.
+func (adapter *Adapter) HealthChecks() []kurin.HealthCheck {
+	return []kurin.HealthCheck{{Name: adapter.checkName, Critical: true, Check: adapter.db.PingContext}}
+}
+
+func (adapter *Adapter) register(collector prometheus.Collector) {
+	if err := adapter.registerer.Register(collector); err != nil {
+		adapter.logger.Warn(fmt.Sprintf("Unable to register the sql metrics, give every adapter its own registerer: %s", err))
+	}
+}
+
+func (adapter *Adapter) Open() {
+	adapter.logger.Info(fmt.Sprintf("Checking database every %s...", adapter.interval))
+	adapter.check()
+
+	ticker := time.NewTicker(adapter.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			adapter.check()
+		case <-adapter.stop:
+			return
+		}
+	}
+}
+
+func (adapter *Adapter) check() {
+	stats := adapter.db.Stats()
+	adapter.openConnections.Set(float64(stats.OpenConnections))
+	adapter.connections.WithLabelValues("in_use").Set(float64(stats.InUse))
+	adapter.connections.WithLabelValues("idle").Set(float64(stats.Idle))
+	adapter.waitCount.Set(float64(stats.WaitCount))
+	adapter.waitDuration.Set(stats.WaitDuration.Seconds())
+	adapter.maxOpen.Set(float64(stats.MaxOpenConnections))
+
+	ctx, cancel := context.WithTimeout(context.Background(), adapter.interval)
+	defer cancel()
+	err := adapter.db.PingContext(ctx)
+
+	adapter.mu.Lock()
+	defer adapter.mu.Unlock()
+
+	if (err == nil) == adapter.healthy {
+		return
+	}
+	adapter.healthy = err == nil
+
+	if err != nil {
+		adapter.logger.Error(fmt.Sprintf("health check to database failed: %s", err))
+		err = fmt.Errorf("%w: database: %w", kurin.ErrDependency, err)
+	} else {
+		adapter.logger.Info("database is reachable again")
+	}
+	adapter.fail.Fail(err)
+}
+
+func (adapter *Adapter) Close() {
+	adapter.stopOnce.Do(func() {
+		close(adapter.stop)
+	})
+
+	if err := adapter.db.Close(); err != nil {
+		adapter.logger.Error(fmt.Sprintf("unable to close database properly: %s", err))
+	}
+}
+
+func (adapter *Adapter) NotifyFail(c chan error) {
+	adapter.fail.NotifyFail(c)
+}
+
+func (adapter *Adapter) OnFailure(err error) {
+}
@@ -0,0 +1,311 @@
+package websocket
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/maxperrimond/kurin"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type (
+	// Adapter serves a WebSocket endpoint, keeping track of the open connections so that they are
+	// closed gracefully on Close: the clients get a close frame and are given some time to
+	// disconnect before their connections are closed. The connections failing on the server side
+	// are reported to the application, see NotifyFail.
+	Adapter struct {
+		srv          *http.Server
+		upgrader     websocket.Upgrader
+		handler      Handler
+		logger       kurin.Logger
+		closeTimeout time.Duration
+		clock        kurin.Clock
+		registerer   prometheus.Registerer
+		activeConns  prometheus.Gauge
+		conns        map[*websocket.Conn]struct{}
+		closed       bool
+		failing      bool
+		fail         kurin.FailNotifier
+		name         string
+		mu           sync.Mutex
+		wg           sync.WaitGroup
+	}
+
+	// Handler serves a connection, until it returns and the connection is closed. It should read
+	// the connection until it fails, which is how the close frame of the client is received.
+	Handler func(conn *websocket.Conn)
+
+	Option func(*Adapter)
+)
+
+func NewWebSocketAdapter(path string, handler Handler, host string, port int, logger kurin.Logger, options ...Option) kurin.Adapter {
+	adapter := &Adapter{
+		handler:      handler,
+		logger:       logger,
+		closeTimeout: 5 * time.Second,
+		clock:        kurin.SystemClock,
+		registerer:   prometheus.DefaultRegisterer,
+		conns:        make(map[*websocket.Conn]struct{}),
+		activeConns: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "app_websocket_connections",
+			Help: "Number of open WebSocket connections.",
+		}),
+	}
+
+	for _, option := range options {
+		option(adapter)
+	}
+
+	if err := adapter.registerer.Register(adapter.activeConns); err != nil {
+		adapter.logger.Warn(fmt.Sprintf("Unable to register the websocket metrics, give every adapter its own registerer: %s", err))
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(path, adapter.serve)
+	adapter.srv = &http.Server{
+		Addr:    fmt.Sprintf("%s:%d", host, port),
+		Handler: mux,
+	}
+
+	return adapter
+}
+
+// WithCloseTimeout sets how long the clients have to disconnect once they got the close frame,
+// 5s by default.
+func WithCloseTimeout(d time.Duration) Option {
+	return func(adapter *Adapter) {
+		adapter.closeTimeout = d
+	}
+}
+
+// WithClock sets the clock timing the close timeout, the system clock by default. The deadlines of
+// the connections stay on the system time.
+func WithClock(clock kurin.Clock) Option {
+	return func(adapter *Adapter) {
+		adapter.clock = clock
+	}
+}
+
+// WithCheckOrigin sets the check of the Origin header of the upgrade requests, which by default
+// must match their Host.
+func WithCheckOrigin(check func(r *http.Request) bool) Option {
+	return func(adapter *Adapter) {
+		adapter.upgrader.CheckOrigin = check
+	}
+}
+
+// WithName names the adapter, to tell it apart from the other adapters of the application in its
+// logs, events and metrics, see kurin.Named.
+func WithName(name string) Option {
+	return func(adapter *Adapter) {
+		adapter.name = name
+	}
+}
+
+// WithRegisterer sets the registerer of the app_websocket_connections gauge,
+// prometheus.DefaultRegisterer by default.
+func WithRegisterer(registerer prometheus.Registerer) Option {
+	return func(adapter *Adapter) {
+		adapter.registerer = registerer
+	}
+}
+
+func (adapter *Adapter) serve(w http.ResponseWriter, r *http.Request) {
+	adapter.mu.Lock()
+	closed := adapter.closed
+	adapter.mu.Unlock()
+	if closed {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	uw := &upgradeWriter{ResponseWriter: w}
+	conn, err := adapter.upgrader.Upgrade(uw, r, nil)
+	if err != nil {
+		// The upgrader already answered the error, only the failures of the server, answered with
+		// a 5xx, are reported: the others are those of the client, e.g. gone before the upgrade.
+		if uw.status < http.StatusInternalServerError {
+			adapter.logger.Debug(fmt.Sprintf("Unable to upgrade the connection: %s", err))
+			return
+		}
+		adapter.setFailing(err)
+		return
+	}
+	adapter.setFailing(nil)
+
+	if !adapter.track(conn) {
+		conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, ""), time.Now().Add(time.Second))
+		conn.Close()
+		return
+	}
+	defer adapter.untrack(conn)
+
+	adapter.handler(conn)
+}
+
+func (adapter *Adapter) track(conn *websocket.Conn) bool {
+	adapter.mu.Lock()
+	defer adapter.mu.Unlock()
+
+	if adapter.closed {
+		return false
+	}
+	adapter.conns[conn] = struct{}{}
+	adapter.wg.Add(1)
+	adapter.activeConns.Inc()
+
+	return true
+}
+
+func (adapter *Adapter) untrack(conn *websocket.Conn) {
+	conn.Close()
+
+	adapter.mu.Lock()
+	delete(adapter.conns, conn)
+	adapter.mu.Unlock()
+	adapter.activeConns.Dec()
+	adapter.wg.Done()
+}
+
+func (adapter *Adapter) Open() {
+	if err := adapter.OpenContext(context.Background()); err != nil {
+		adapter.logger.Fatal(err)
+	}
+}
+
+// OpenContext serves until the server is shut down or ctx is cancelled, leaving the shutdown to
+// Close in that case.
+func (adapter *Adapter) OpenContext(ctx context.Context) error {
+	listener, err := net.Listen("tcp", adapter.srv.Addr)
+	if err != nil {
+		return fmt.Errorf("%w: %w", kurin.ErrBind, err)
+	}
+
+	adapter.logger.Info(fmt.Sprintf("Serving WebSocket on ws://%s", listener.Addr()))
+	served := make(chan error, 1)
+	go func() {
+		served <- adapter.srv.Serve(listener)
+	}()
+
+	select {
+	case err := <-served:
+		if err == http.ErrServerClosed {
+			return nil
+		}
+		return err
+	case <-ctx.Done():
+		return nil
+	}
+}
+
+func (adapter *Adapter) Close() {
+	if err := adapter.CloseContext(context.Background()); err != nil {
+		adapter.logger.Error(err)
+	}
+}
+
+// CloseContext stops accepting connections, sends a close frame to the open ones and waits for
+// the clients to disconnect, within the close timeout and until ctx is done, before closing the
+// remaining connections.
+func (adapter *Adapter) CloseContext(ctx context.Context) error {
+	err := adapter.srv.Shutdown(ctx)
+
+	adapter.mu.Lock()
+	adapter.closed = true
+	conns := make([]*websocket.Conn, 0, len(adapter.conns))
+	for conn := range adapter.conns {
+		conns = append(conns, conn)
+	}
+	adapter.mu.Unlock()
+
+	adapter.logger.Info(fmt.Sprintf("Closing %d WebSocket connections...", len(conns)))
+	deadline := time.Now().Add(adapter.closeTimeout)
+	closeMessage := websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down")
+	for _, conn := range conns {
+		conn.WriteControl(websocket.CloseMessage, closeMessage, deadline)
+	}
+
+	disconnected := make(chan struct{})
+	go func() {
+		adapter.wg.Wait()
+		close(disconnected)
+	}()
+
+	select {
+	case <-disconnected:
+		return err
+	case <-adapter.clock.After(adapter.closeTimeout):
+	case <-ctx.Done():
+	}
+
+	adapter.mu.Lock()
+	adapter.logger.Warn(fmt.Sprintf("Forcing %d WebSocket connections closed", len(adapter.conns)))
+	for conn := range adapter.conns {
+		conn.Close()
+	}
+	adapter.mu.Unlock()
+
+	return err
+}
+
+// Name is the name given by WithName, if any.
+func (adapter *Adapter) Name() string {
+	return adapter.name
+}
+
+// setFailing reports the connection failure to the application, or its recovery once a
+// connection is established again (nil error), on changes only.
+func (adapter *Adapter) setFailing(err error) {
+	adapter.mu.Lock()
+	defer adapter.mu.Unlock()
+
+	if (err != nil) == adapter.failing {
+		return
+	}
+	adapter.failing = err != nil
+
+	if err != nil {
+		adapter.logger.Error(fmt.Sprintf("Unable to establish the WebSocket connection: %s", err))
+		err = fmt.Errorf("websocket: %w", err)
+	} else {
+		adapter.logger.Info("WebSocket connections are established again")
+	}
+	adapter.fail.Fail(err)
+}
+
+// NotifyFail subscribes c to the connection failures, and their recovery.
+func (adapter *Adapter) NotifyFail(c chan error) {
+	adapter.fail.NotifyFail(c)
+}
+
+// OnFailure keeps serving the connections, the failures of the other systems not concerning them.
+func (adapter *Adapter) OnFailure(err error) {
+}
+
+// upgradeWriter keeps the status the upgrader answers an error with, to tell apart the failures of
+// the server.
+type upgradeWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *upgradeWriter) WriteHeader(code int) {
+	w.status = code
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *upgradeWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("websocket: response does not implement http.Hijacker")
+	}
+
+	return hijacker.Hijack()
+}
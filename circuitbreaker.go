@@ -0,0 +1,235 @@
+package kurin
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+type (
+	CircuitState int
+
+	// CircuitBreaker stops calling a failing dependency once failureThreshold consecutive calls
+	// failed. After openDuration, up to halfOpenProbes calls are let through: the circuit closes
+	// again when all of them succeed and re-opens on the first failure.
+	CircuitBreaker struct {
+		failureThreshold int
+		openDuration     time.Duration
+		halfOpenProbes   int
+
+		mu            sync.Mutex
+		state         CircuitState
+		generation    uint64
+		failures      int
+		probes        int
+		successes     int
+		openedAt      time.Time
+		clock         Clock
+		fail          chan error
+		pending       []error
+		notifying     bool
+		onStateChange []func(from, to CircuitState)
+	}
+)
+
+const (
+	CircuitClosed CircuitState = iota
+	CircuitOpen
+	CircuitHalfOpen
+)
+
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+func NewCircuitBreaker(failureThreshold int, openDuration time.Duration, halfOpenProbes int) *CircuitBreaker {
+	if failureThreshold < 1 {
+		failureThreshold = 1
+	}
+	if halfOpenProbes < 1 {
+		halfOpenProbes = 1
+	}
+
+	return &CircuitBreaker{
+		failureThreshold: failureThreshold,
+		openDuration:     openDuration,
+		halfOpenProbes:   halfOpenProbes,
+		clock:            SystemClock,
+	}
+}
+
+// SetClock sets the clock timing the open circuit, the system clock by default.
+func (cb *CircuitBreaker) SetClock(clock Clock) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	cb.clock = clock
+}
+
+func (state CircuitState) String() string {
+	switch state {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	}
+
+	return "unknown"
+}
+
+// Execute calls fn unless the circuit is open, in which case ErrCircuitOpen is returned right away.
+func (cb *CircuitBreaker) Execute(fn func() error) error {
+	generation, ok := cb.allow()
+	if !ok {
+		return ErrCircuitOpen
+	}
+
+	err := fn()
+	cb.record(generation, err == nil)
+
+	return err
+}
+
+func (cb *CircuitBreaker) State() CircuitState {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	return cb.state
+}
+
+// RetryAfter returns how long the circuit stays open before letting probes through, 0 unless it
+// is open.
+func (cb *CircuitBreaker) RetryAfter() time.Duration {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	if cb.state != CircuitOpen {
+		return 0
+	}
+	if remaining := cb.openDuration - cb.clock.Now().Sub(cb.openedAt); remaining > 0 {
+		return remaining
+	}
+
+	return 0
+}
+
+// NotifyFail reports ErrCircuitOpen on the given channel every time the circuit opens, and nil
+// when it closes again, in the order of the transitions.
+func (cb *CircuitBreaker) NotifyFail(c chan error) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	cb.fail = c
+}
+
+// OnStateChange registers a function called on every transition, while the breaker is locked.
+func (cb *CircuitBreaker) OnStateChange(fn func(from, to CircuitState)) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	cb.onStateChange = append(cb.onStateChange, fn)
+}
+
+func (cb *CircuitBreaker) allow() (uint64, bool) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	switch cb.state {
+	case CircuitOpen:
+		if cb.clock.Now().Sub(cb.openedAt) < cb.openDuration {
+			return 0, false
+		}
+		cb.setState(CircuitHalfOpen)
+		fallthrough
+	case CircuitHalfOpen:
+		if cb.probes >= cb.halfOpenProbes {
+			return 0, false
+		}
+		cb.probes++
+	}
+
+	return cb.generation, true
+}
+
+func (cb *CircuitBreaker) record(generation uint64, success bool) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	// The outcome of a call started before the last transition says nothing about the current state,
+	// e.g. a slow call admitted while closed must not count as a half-open probe.
+	if generation != cb.generation {
+		return
+	}
+
+	switch cb.state {
+	case CircuitClosed:
+		if success {
+			cb.failures = 0
+			return
+		}
+		cb.failures++
+		if cb.failures >= cb.failureThreshold {
+			cb.setState(CircuitOpen)
+		}
+	case CircuitHalfOpen:
+		if !success {
+			cb.setState(CircuitOpen)
+			return
+		}
+		cb.successes++
+		if cb.successes >= cb.halfOpenProbes {
+			cb.setState(CircuitClosed)
+		}
+	}
+}
+
+func (cb *CircuitBreaker) setState(state CircuitState) {
+	from := cb.state
+	cb.state = state
+	cb.generation++
+	cb.failures = 0
+	cb.probes = 0
+	cb.successes = 0
+
+	switch {
+	case state == CircuitOpen:
+		cb.openedAt = cb.clock.Now()
+		cb.notify(ErrCircuitOpen)
+	case state == CircuitClosed && from == CircuitHalfOpen:
+		cb.notify(nil)
+	}
+
+	for _, fn := range cb.onStateChange {
+		fn(from, state)
+	}
+}
+
+// notify queues err for the channel of NotifyFail, sent by a single goroutine so that the
+// notifications keep their order without blocking the breaker.
+func (cb *CircuitBreaker) notify(err error) {
+	if cb.fail == nil {
+		return
+	}
+
+	cb.pending = append(cb.pending, err)
+	if !cb.notifying {
+		cb.notifying = true
+		go cb.sendPending(cb.fail)
+	}
+}
+
+func (cb *CircuitBreaker) sendPending(c chan error) {
+	for {
+		cb.mu.Lock()
+		if len(cb.pending) == 0 {
+			cb.notifying = false
+			cb.mu.Unlock()
+			return
+		}
+		err := cb.pending[0]
+		cb.pending = cb.pending[1:]
+		cb.mu.Unlock()
+
+		c <- err
+	}
+}
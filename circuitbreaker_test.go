@@ -0,0 +1,58 @@
+package kurin
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreakerTransitions(t *testing.T) {
+	clock := NewMockClock(time.Now())
+	cb := NewCircuitBreaker(2, time.Minute, 1)
+	cb.SetClock(clock)
+	boom := errors.New("boom")
+
+	cb.Execute(func() error { return boom })
+	cb.Execute(func() error { return boom })
+	if cb.State() != CircuitOpen {
+		t.Fatalf("got %s, want open", cb.State())
+	}
+	if err := cb.Execute(func() error { return nil }); err != ErrCircuitOpen {
+		t.Fatalf("got %v, want ErrCircuitOpen", err)
+	}
+	if d := cb.RetryAfter(); d != time.Minute {
+		t.Errorf("got retry after %s, want 1m", d)
+	}
+
+	clock.Add(time.Minute)
+	if err := cb.Execute(func() error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if cb.State() != CircuitClosed {
+		t.Fatalf("got %s, want closed", cb.State())
+	}
+}
+
+func TestCircuitBreakerNotifiesInOrder(t *testing.T) {
+	clock := NewMockClock(time.Now())
+	cb := NewCircuitBreaker(1, time.Second, 1)
+	cb.SetClock(clock)
+	c := make(chan error)
+	cb.NotifyFail(c)
+	boom := errors.New("boom")
+
+	for i := 0; i < 50; i++ {
+		cb.Execute(func() error { return boom })
+		clock.Add(time.Second)
+		cb.Execute(func() error { return nil })
+	}
+
+	for i := 0; i < 50; i++ {
+		if err := <-c; err != ErrCircuitOpen {
+			t.Fatalf("notification %d: got %v, want ErrCircuitOpen", 2*i, err)
+		}
+		if err := <-c; err != nil {
+			t.Fatalf("notification %d: got %v, want nil", 2*i+1, err)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package kurin
+
+import (
+	"sync"
+	"time"
+)
+
+type (
+	// Clock tells the time to the time dependent code, so that it can be tested with a MockClock.
+	Clock interface {
+		Now() time.Time
+		After(d time.Duration) <-chan time.Time
+	}
+
+	systemClock struct{}
+
+	// MockClock is a Clock whose time only moves forward with Add, sending on the channels returned
+	// by After once their duration elapsed.
+	MockClock struct {
+		mu      sync.Mutex
+		now     time.Time
+		waiters []mockWaiter
+	}
+
+	mockWaiter struct {
+		deadline time.Time
+		c        chan time.Time
+	}
+)
+
+// SystemClock is the Clock of the time package, the default one.
+var SystemClock Clock = systemClock{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+func NewMockClock(now time.Time) *MockClock {
+	return &MockClock{now: now}
+}
+
+func (clock *MockClock) Now() time.Time {
+	clock.mu.Lock()
+	defer clock.mu.Unlock()
+
+	return clock.now
+}
+
+func (clock *MockClock) After(d time.Duration) <-chan time.Time {
+	clock.mu.Lock()
+	defer clock.mu.Unlock()
+
+	c := make(chan time.Time, 1)
+	if d <= 0 {
+		c <- clock.now
+		return c
+	}
+	clock.waiters = append(clock.waiters, mockWaiter{deadline: clock.now.Add(d), c: c})
+
+	return c
+}
+
+// Add moves the time forward by d.
+func (clock *MockClock) Add(d time.Duration) {
+	clock.mu.Lock()
+	defer clock.mu.Unlock()
+
+	clock.now = clock.now.Add(d)
+	waiters := clock.waiters[:0]
+	for _, waiter := range clock.waiters {
+		if clock.now.Before(waiter.deadline) {
+			waiters = append(waiters, waiter)
+			continue
+		}
+		waiter.c <- clock.now
+	}
+	clock.waiters = waiters
+}
@@ -0,0 +1,14 @@
+package kurin
+
+import "runtime"
+
+// DefaultConcurrency is a number of workers suited to the adapters processing work in parallel,
+// whose work needs no ordering: GOMAXPROCS, which follows the CPU quota of the container with Go
+// 1.25 and later, or with go.uber.org/automaxprocs imported by the application on older versions.
+func DefaultConcurrency() int {
+	if n := runtime.GOMAXPROCS(0); n > 0 {
+		return n
+	}
+
+	return 1
+}
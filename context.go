@@ -0,0 +1,43 @@
+package kurin
+
+import "context"
+
+type contextKey int
+
+const (
+	requestIDKey contextKey = iota
+	loggerKey
+)
+
+// ContextWithRequestID returns a copy of ctx carrying the request ID.
+func ContextWithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey, id)
+}
+
+// RequestID returns the request ID carried by ctx, if any.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+
+	return id
+}
+
+// ContextWithLogger returns a copy of ctx carrying the logger, to be retrieved with LoggerFromContext.
+func ContextWithLogger(ctx context.Context, logger Logger) context.Context {
+	return context.WithValue(ctx, loggerKey, logger)
+}
+
+// LoggerFromContext returns the logger carried by ctx, e.g. the one decorated with the request ID
+// by the HTTP adapter. Without one, it returns the default logger decorated with the request ID
+// carried by ctx, if any.
+func LoggerFromContext(ctx context.Context) Logger {
+	if logger, ok := ctx.Value(loggerKey).(Logger); ok {
+		return logger
+	}
+
+	logger := NewDefaultLogger()
+	if id := RequestID(ctx); id != "" {
+		logger = WithFields(logger, "request_id", id)
+	}
+
+	return logger
+}
@@ -0,0 +1,41 @@
+package kurin
+
+import "errors"
+
+type (
+	// HTTPError is an error knowing how it should be answered to an HTTP client: with which status,
+	// and under which category, a stable identifier of its kind.
+	HTTPError interface {
+		error
+		StatusCode() int
+		Category() string
+	}
+)
+
+// Adapters wrap their errors with these to tell main which exit code to use, see ExitCode.
+var (
+	ErrBind       = errors.New("unable to bind")
+	ErrConfig     = errors.New("invalid configuration")
+	ErrDependency = errors.New("dependency unavailable")
+)
+
+// ExitCode returns the process exit code for the error returned by Run:
+//   - 0 for nil,
+//   - 2 for ErrConfig,
+//   - 3 for ErrBind,
+//   - 4 for ErrDependency,
+//   - 1 for any other error.
+func ExitCode(err error) int {
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, ErrConfig):
+		return 2
+	case errors.Is(err, ErrBind):
+		return 3
+	case errors.Is(err, ErrDependency):
+		return 4
+	}
+
+	return 1
+}
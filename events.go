@@ -0,0 +1,65 @@
+package kurin
+
+import "fmt"
+
+type (
+	EventType int
+
+	// Event describes a lifecycle transition of one of the application systems, Adapter being
+	// the name of the adapter or system it concerns.
+	Event struct {
+		Type    EventType
+		Adapter string
+		Err     error
+	}
+
+	failure struct {
+		system Fallible
+		source int // the index of system among the fallible systems
+		index  int // the index of system among the adapters, -1 if it is not one
+		err    error
+	}
+)
+
+// EventOpened is emitted once the adapter was started, like AdapterRunning, and not once it is
+// listening.
+const (
+	EventOpened EventType = iota
+	EventClosed
+	EventUnhealthy
+	EventRecovered
+)
+
+func (t EventType) String() string {
+	switch t {
+	case EventOpened:
+		return "opened"
+	case EventClosed:
+		return "closed"
+	case EventUnhealthy:
+		return "unhealthy"
+	case EventRecovered:
+		return "recovered"
+	}
+
+	return "unknown"
+}
+
+// OnEvent registers a hook called synchronously for every lifecycle event of the application.
+func (a *App) OnEvent(fn func(Event)) {
+	a.eventHooks = append(a.eventHooks, fn)
+}
+
+func (a *App) emit(event Event) {
+	for _, hook := range a.eventHooks {
+		hook(event)
+	}
+}
+
+func systemName(system interface{}) string {
+	if named, ok := system.(Named); ok && named.Name() != "" {
+		return named.Name()
+	}
+
+	return fmt.Sprintf("%T", system)
+}
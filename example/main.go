@@ -1,6 +1,9 @@
 package main
 
 import (
+	"context"
+	"os"
+
 	"github.com/maxperrimond/kurin"
 	"github.com/maxperrimond/kurin/example/adapters/http"
 	"github.com/maxperrimond/kurin/example/engine"
@@ -21,5 +24,8 @@ func main() {
 	// App
 	a := kurin.NewApp("Example", http.NewHTTPAdapter(e, "iam", 7272, logger))
 	a.RegisterSystems(exampleProviderFactory)
-	a.Run()
+	if err := a.Run(context.Background()); err != nil {
+		logger.Error("Application failed", zap.Error(err))
+		os.Exit(kurin.ExitCode(err))
+	}
 }
@@ -0,0 +1,52 @@
+package kurin
+
+import "sync"
+
+// FailNotifier implements Fallible for the systems embedding it, which call Fail on their failures
+// and recoveries. The notifications are sent in order by a single goroutine, without blocking the
+// system, and dropped until a channel is given to NotifyFail.
+type FailNotifier struct {
+	mu        sync.Mutex
+	fail      chan error
+	pending   []error
+	notifying bool
+}
+
+// NotifyFail sets the channel the failures are sent on, replacing the previous one.
+func (notifier *FailNotifier) NotifyFail(c chan error) {
+	notifier.mu.Lock()
+	defer notifier.mu.Unlock()
+
+	notifier.fail = c
+}
+
+// Fail queues err, or nil once recovered, for the channel of NotifyFail.
+func (notifier *FailNotifier) Fail(err error) {
+	notifier.mu.Lock()
+	defer notifier.mu.Unlock()
+
+	if notifier.fail == nil {
+		return
+	}
+	notifier.pending = append(notifier.pending, err)
+	if !notifier.notifying {
+		notifier.notifying = true
+		go notifier.sendPending()
+	}
+}
+
+func (notifier *FailNotifier) sendPending() {
+	for {
+		notifier.mu.Lock()
+		if len(notifier.pending) == 0 {
+			notifier.notifying = false
+			notifier.mu.Unlock()
+			return
+		}
+		err, c := notifier.pending[0], notifier.fail
+		notifier.pending = notifier.pending[1:]
+		notifier.mu.Unlock()
+
+		c <- err
+	}
+}
@@ -0,0 +1,135 @@
+package kurin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+const (
+	HealthStatusHealthy   = "healthy"
+	HealthStatusUnhealthy = "unhealthy"
+)
+
+type (
+	// Healther adapters report whether they are healthy, to be part of the application health.
+	Healther interface {
+		IsHealthy() bool
+	}
+
+	// HealthReport is the aggregate health of the application, with the status of every Healther
+	// adapter by name, see Named. A name appearing more than once gets its rank appended, e.g.
+	// "*http.Adapter#2".
+	HealthReport struct {
+		Status   string            `json:"status"`
+		Adapters map[string]string `json:"adapters"`
+	}
+
+	// HealthCheck is a named check of a dependency, e.g. a database ping, failing with an error.
+	// A failing critical check makes the application unhealthy, a failing non critical one only
+	// degrades it.
+	HealthCheck struct {
+		Name     string
+		Critical bool
+		Check    func(ctx context.Context) error
+	}
+
+	// HealthChecker systems check their dependencies, their checks being added to the health
+	// registry of the application by RegisterSystems, e.g. the ping of the redis adapter.
+	HealthChecker interface {
+		HealthChecks() []HealthCheck
+	}
+
+	// HealthRegistryUser systems run the health checks of the application, given its registry by
+	// RegisterSystems, like the HTTP adapter on its health probes.
+	HealthRegistryUser interface {
+		UseHealthRegistry(registry *HealthRegistry)
+	}
+
+	// HealthRegistry holds the health checks of the dependencies of an application, by unique name.
+	HealthRegistry struct {
+		mu     sync.RWMutex
+		checks []HealthCheck
+	}
+)
+
+// ErrDuplicateHealthCheck is returned when registering a health check under a name already taken.
+var ErrDuplicateHealthCheck = errors.New("duplicate health check")
+
+func NewHealthRegistry() *HealthRegistry {
+	return &HealthRegistry{}
+}
+
+// Register adds the check, failing with ErrDuplicateHealthCheck, an ErrConfig, when another check
+// is registered under its name.
+func (registry *HealthRegistry) Register(check HealthCheck) error {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	for _, registered := range registry.checks {
+		if registered.Name == check.Name {
+			return fmt.Errorf("%w: %w: %q", ErrConfig, ErrDuplicateHealthCheck, check.Name)
+		}
+	}
+	registry.checks = append(registry.checks, check)
+
+	return nil
+}
+
+// Checks returns the registered checks, in the order they were registered.
+func (registry *HealthRegistry) Checks() []HealthCheck {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+
+	return append([]HealthCheck(nil), registry.checks...)
+}
+
+// RegisterHealthCheck adds a check to the health registry of the application, run by the systems
+// using it like the HTTP adapter. It fails with ErrDuplicateHealthCheck when the name is taken.
+func (a *App) RegisterHealthCheck(name string, critical bool, check func(ctx context.Context) error) error {
+	return a.healthChecks.Register(HealthCheck{Name: name, Critical: critical, Check: check})
+}
+
+// HealthRegistry returns the health registry of the application.
+func (a *App) HealthRegistry() *HealthRegistry {
+	return a.healthChecks
+}
+
+// Health reports the application as healthy only when every Healther adapter is.
+func (a *App) Health() HealthReport {
+	report := HealthReport{Status: HealthStatusHealthy, Adapters: make(map[string]string)}
+	names := a.adapterNames()
+	for i, adapter := range a.adapters {
+		healther, ok := adapter.(Healther)
+		if !ok {
+			continue
+		}
+
+		status := HealthStatusHealthy
+		if !healther.IsHealthy() {
+			status = HealthStatusUnhealthy
+			report.Status = HealthStatusUnhealthy
+		}
+		report.Adapters[names[i]] = status
+	}
+
+	return report
+}
+
+// HealthHandler answers the health report as JSON, with a 503 status when unhealthy. It is meant to
+// be mounted on the router of an HTTP adapter as the single health endpoint of the application.
+func (a *App) HealthHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := a.Health()
+		status := http.StatusOK
+		if report.Status != HealthStatusHealthy {
+			status = http.StatusServiceUnavailable
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(report)
+	})
+}
@@ -1,10 +1,16 @@
 package kurin
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/signal"
+	"reflect"
+	"sync"
 	"syscall"
+	"time"
+
+	"golang.org/x/sync/errgroup"
 )
 
 type (
@@ -14,9 +20,28 @@ type (
 		adapters        []Adapter
 		fallibleSystems []Fallible
 		closableSystems []Closable
-		fail            chan error
+		// fallibleIndexes and closableIndexes are the indexes in adapters of the fallible and
+		// closable systems, -1 for the systems which are not adapters.
+		fallibleIndexes []int
+		closableIndexes []int
+		// failing holds the last failure of every fallible system, nil once recovered.
+		failing []error
+		fail            chan failure
+		eventHooks      []func(Event)
+		closeTimeouts   []closeTimeout
+		gracePeriod     time.Duration
+		healthChecks    *HealthRegistry
+		err             error
+		mu              sync.Mutex
+		states          []AdapterState
+	}
+
+	closeTimeout struct {
+		closable Closable
+		timeout  time.Duration
 	}
 
+	// Fallible systems report their failures on the given channel, and a nil error once they recovered.
 	Fallible interface {
 		NotifyFail(chan error)
 	}
@@ -30,16 +55,59 @@ type (
 		Open()
 		OnFailure(error)
 	}
+
+	// ContextOpener adapters are opened with OpenContext instead of Open. The context is cancelled
+	// when the application shuts down, before the adapters are closed. A returned error is fatal
+	// to the application, which then shuts down and returns it from Run.
+	ContextOpener interface {
+		OpenContext(ctx context.Context) error
+	}
+
+	// ContextCloser systems are closed with CloseContext instead of Close.
+	ContextCloser interface {
+		CloseContext(ctx context.Context) error
+	}
+
+	// TimedCloser systems are closed with a context expiring after their shutdown timeout, if
+	// positive, instead of a context only bounded by the grace period of the application.
+	TimedCloser interface {
+		ShutdownTimeout() time.Duration
+	}
+
+	// Named systems are called by their name in the logs, events and metrics of the application
+	// instead of their type, to tell apart several adapters of the same type.
+	Named interface {
+		Name() string
+	}
+
+	// Describer adapters report where they can be reached, for instance to register them in a
+	// service discovery. The descriptor is only complete once the adapter is listening.
+	Describer interface {
+		Descriptor() Descriptor
+	}
+
+	Descriptor struct {
+		Protocol   string
+		Address    string
+		Port       int
+		HealthPath string
+	}
 )
 
 func NewApp(name string, adapters ...Adapter) *App {
 	app := &App{
 		name:            name,
 		adapters:        adapters,
+		states:          make([]AdapterState, len(adapters)),
 		closableSystems: make([]Closable, 0),
 		fallibleSystems: make([]Fallible, 0),
+		healthChecks:    NewHealthRegistry(),
+	}
+	for i, adapter := range adapters {
+		if _, ok := adapter.(*migrationStep); !ok {
+			app.registerSystem(adapter, i)
+		}
 	}
-	app.RegisterSystems(adapters)
 
 	return app
 }
@@ -48,57 +116,278 @@ func (a *App) SetLogger(logger Logger) {
 	a.logger = logger
 }
 
+// SetShutdownTimeout sets the deadline of the context given to CloseContext for c, overriding the
+// one c reports as a TimedCloser. A zero timeout closes c without deadline but the grace period of
+// SetShutdownGracePeriod, if any. c is told apart from the other systems by equality, so it must be
+// of a comparable type, like a pointer: the timeout of another one is ignored.
+func (a *App) SetShutdownTimeout(c Closable, timeout time.Duration) {
+	for i := range a.closeTimeouts {
+		if sameSystem(a.closeTimeouts[i].closable, c) {
+			a.closeTimeouts[i].timeout = timeout
+			return
+		}
+	}
+	a.closeTimeouts = append(a.closeTimeouts, closeTimeout{c, timeout})
+}
+
+// SetShutdownGracePeriod bounds the whole shutdown by d: every ContextCloser system is closed with
+// a context expiring d after the shutdown started, or earlier with its own shutdown timeout, so that
+// the systems closed once it passed are forced closed right away. The other systems are given up on,
+// left closing in the background, once it passed. A zero period keeps the shutdown unbounded but by
+// the timeouts of the systems.
+func (a *App) SetShutdownGracePeriod(d time.Duration) {
+	a.gracePeriod = d
+}
+
+// Descriptors returns the descriptors of the Describer adapters.
+func (a *App) Descriptors() []Descriptor {
+	descriptors := make([]Descriptor, 0)
+	for _, adapter := range a.adapters {
+		if describer, ok := adapter.(Describer); ok {
+			descriptors = append(descriptors, describer.Descriptor())
+		}
+	}
+
+	return descriptors
+}
+
+// RegisterSystems adds the systems to the application: the Fallible ones report their failures, the
+// Closable ones are closed on shutdown, the checks of the HealthChecker ones are added to the health
+// registry and the HealthRegistryUser ones are given it. A health check registered twice makes Run
+// fail with ErrDuplicateHealthCheck.
 func (a *App) RegisterSystems(systems ...interface{}) {
 	for _, s := range systems {
-		if f, ok := s.(Fallible); ok {
-			a.fallibleSystems = append(a.fallibleSystems, f)
-		}
+		a.registerSystem(s, -1)
+	}
+}
+
+// registerSystem registers s, the adapter of the given index or -1 if s is not an adapter.
+func (a *App) registerSystem(s interface{}, index int) {
+	if f, ok := s.(Fallible); ok {
+		a.fallibleSystems = append(a.fallibleSystems, f)
+		a.fallibleIndexes = append(a.fallibleIndexes, index)
+	}
+
+	if c, ok := s.(Closable); ok {
+		a.closableSystems = append(a.closableSystems, c)
+		a.closableIndexes = append(a.closableIndexes, index)
+	}
 
-		if c, ok := s.(Closable); ok {
-			a.closableSystems = append(a.closableSystems, c)
+	if checker, ok := s.(HealthChecker); ok {
+		for _, check := range checker.HealthChecks() {
+			if err := a.healthChecks.Register(check); err != nil && a.err == nil {
+				a.err = err
+			}
 		}
 	}
+
+	if user, ok := s.(HealthRegistryUser); ok {
+		user.UseHealthRegistry(a.healthChecks)
+	}
 }
 
-func (a *App) Run() {
+// Run opens the adapters and blocks until the application shuts down, closing every
+// closable system before returning.
+//
+// The application shuts down cleanly, returning nil, on SIGINT, SIGTERM or once ctx is done,
+// whichever comes first driving the shutdown, so that Run can be embedded in a larger program or
+// a test cancelling ctx. A signal received while shutting down forces the process to exit.
+// It shuts down returning the error of the first ContextOpener adapter whose OpenContext
+// fails, or of a failing MigrationStep, which are the only kinds of fatal errors: the context
+// given to the other adapters is then cancelled. Failures reported by Fallible systems are
+// recoverable, they are forwarded to every adapter through OnFailure and do not stop the
+// application. A recovery is forwarded as a nil error once every fallible system recovered.
+func (a *App) Run(ctx context.Context) error {
 	if a.logger == nil {
-		a.logger = newDefaultLogger()
+		a.logger = NewDefaultLogger()
+	}
+	if a.err != nil {
+		return a.err
 	}
 
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
-	defer close(stop)
+	defer signal.Stop(stop)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	group, groupCtx := errgroup.WithContext(ctx)
 
 	a.logger.Info(fmt.Sprintf("Starting %s application...", a.name))
 
-	a.fail = make(chan error)
-	defer close(a.fail)
+	a.fail = make(chan failure)
+	a.failing = make([]error, len(a.fallibleSystems))
+	// returned stops the forwarders, a.fail being no longer received from once Run returns.
+	returned := make(chan struct{})
+	defer close(returned)
 
-	for _, system := range a.fallibleSystems {
-		system.NotifyFail(a.fail)
+	for i, system := range a.fallibleSystems {
+		c := make(chan error)
+		system.NotifyFail(c)
+		go func(system Fallible, source int, index int, c chan error) {
+			for {
+				select {
+				case err, ok := <-c:
+					if !ok {
+						return
+					}
+					select {
+					case a.fail <- failure{system, source, index, err}:
+					case <-returned:
+						return
+					}
+				case <-returned:
+					return
+				}
+			}
+		}(system, i, a.fallibleIndexes[i], c)
 	}
 
-	for _, adapter := range a.adapters {
-		go adapter.Open()
+	for i, adapter := range a.adapters {
+		if step, ok := adapter.(*migrationStep); ok {
+			proceed, err := a.runStep(groupCtx, step, stop)
+			if err != nil {
+				group.Go(func() error { return err })
+			}
+			if !proceed {
+				break
+			}
+			// A step has no state nor events of its own, it is not reported as an adapter.
+			continue
+		}
+		a.open(groupCtx, group, adapter)
+		a.setState(i, AdapterRunning)
+		a.emit(Event{Type: EventOpened, Adapter: systemName(adapter)})
 	}
 
 	func() {
 		for {
 			select {
-			case err := <-a.fail:
+			case f := <-a.fail:
+				a.setHealthy(f.index, f.err == nil)
+				event := Event{Type: EventUnhealthy, Adapter: systemName(f.system), Err: f.err}
+				if f.err == nil {
+					event.Type = EventRecovered
+				}
+				a.emit(event)
+
+				a.failing[f.source] = f.err
+				err := f.err
+				// A recovery is only forwarded once no system fails anymore, the adapters being
+				// given the failure of one still failing instead.
+				for _, failing := range a.failing {
+					if err == nil && failing != nil {
+						err = failing
+					}
+				}
 				for _, adapter := range a.adapters {
 					adapter.OnFailure(err)
 				}
 				break
+			case <-groupCtx.Done():
+				if ctx.Err() == nil {
+					a.logger.Error("Adapter failed, shutting down")
+				} else {
+					a.logger.Info("Context done, exiting...")
+				}
+				return
 			case <-stop:
+				a.logger.Info("Shutdown signal received, exiting...")
 				return
 			}
 		}
 	}()
 
-	a.logger.Info("Shutdown signal received, exiting...")
+	cancel()
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-stop:
+			a.logger.Error("Shutdown signal received while shutting down, forcing exit")
+			os.Exit(1)
+		case <-done:
+		}
+	}()
+
+	shutdownCtx := context.Background()
+	if a.gracePeriod > 0 {
+		var cancelShutdown context.CancelFunc
+		shutdownCtx, cancelShutdown = context.WithTimeout(shutdownCtx, a.gracePeriod)
+		defer cancelShutdown()
+	}
+	for i, c := range a.closableSystems {
+		a.setState(a.closableIndexes[i], AdapterClosing)
+		a.close(shutdownCtx, c)
+		a.setState(a.closableIndexes[i], AdapterClosed)
+		a.emit(Event{Type: EventClosed, Adapter: systemName(c)})
+	}
+
+	return group.Wait()
+}
+
+// open runs the ContextOpener adapters in the group. The others are opened aside as their
+// failures cannot be returned.
+func (a *App) open(ctx context.Context, group *errgroup.Group, adapter Adapter) {
+	opener, ok := adapter.(ContextOpener)
+	if !ok {
+		go adapter.Open()
+		return
+	}
+
+	group.Go(func() error {
+		if err := opener.OpenContext(ctx); err != nil {
+			return fmt.Errorf("%s: %w", systemName(adapter), err)
+		}
+		return nil
+	})
+}
+
+// close closes c with ctx, bounded by the shutdown timeout of c if any. A system which is not a
+// ContextCloser is closed aside, and given up on once ctx is done.
+func (a *App) close(ctx context.Context, c Closable) {
+	closer, ok := c.(ContextCloser)
+	if !ok {
+		closed := make(chan struct{})
+		go func() {
+			defer close(closed)
+			c.Close()
+		}()
+		select {
+		case <-closed:
+		case <-ctx.Done():
+			a.logger.Error(fmt.Sprintf("Unable to close %s properly: %s", systemName(c), ctx.Err()))
+		}
+		return
+	}
 
-	for _, c := range a.closableSystems {
-		c.Close()
+	if timeout := a.shutdownTimeout(c); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
 	}
+	if err := closer.CloseContext(ctx); err != nil {
+		a.logger.Error(fmt.Sprintf("Unable to close %s properly: %s", systemName(c), err))
+	}
+}
+
+func (a *App) shutdownTimeout(c Closable) time.Duration {
+	for _, closeTimeout := range a.closeTimeouts {
+		if sameSystem(closeTimeout.closable, c) {
+			return closeTimeout.timeout
+		}
+	}
+	if timed, ok := c.(TimedCloser); ok {
+		return timed.ShutdownTimeout()
+	}
+
+	return 0
+}
+
+// sameSystem reports whether x and y are the same system, without panicking like == for the
+// values of uncomparable types, which are never the same.
+func sameSystem(x, y interface{}) bool {
+	vx, vy := reflect.ValueOf(x), reflect.ValueOf(y)
+
+	return vx.IsValid() && vy.IsValid() && vx.Type() == vy.Type() && vx.Comparable() && vx.Equal(vy)
 }
@@ -0,0 +1,236 @@
+package kurin
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+// closeFunc is a Closable of an uncomparable type.
+type closeFunc func()
+
+func (f closeFunc) Close() {
+	f()
+}
+
+func TestShutdownTimeoutOfUncomparableSystem(t *testing.T) {
+	closed := false
+	system := closeFunc(func() { closed = true })
+
+	app := NewApp("test")
+	app.SetLogger(NewStdLogger(io.Discard, LevelError))
+	app.RegisterSystems(system)
+	app.SetShutdownTimeout(system, time.Second)
+	app.SetShutdownTimeout(system, 2*time.Second)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := app.Run(ctx); err != nil {
+		t.Fatalf("got %v, want a clean shutdown", err)
+	}
+	if !closed {
+		t.Error("got the system left open, want it closed")
+	}
+}
+
+// valueAdapter is a Fallible Adapter of an uncomparable type, failing once opened.
+type valueAdapter struct {
+	channels map[string]chan error
+}
+
+func (v valueAdapter) NotifyFail(c chan error) {
+	v.channels["fail"] = c
+}
+
+func (v valueAdapter) Open() {
+	v.channels["fail"] <- errors.New("down")
+}
+
+func (v valueAdapter) Close() {}
+
+func (v valueAdapter) OnFailure(error) {}
+
+func TestStatesOfUncomparableAdapter(t *testing.T) {
+	app := NewApp("test", valueAdapter{channels: make(map[string]chan error)})
+	app.SetLogger(NewStdLogger(io.Discard, LevelError))
+	events := make(chan Event, 8)
+	app.OnEvent(func(event Event) { events <- event })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	returned := make(chan error)
+	go func() { returned <- app.Run(ctx) }()
+
+	for event := range events {
+		if event.Type == EventUnhealthy {
+			break
+		}
+	}
+	if state := app.AdapterStatuses()[0].State; state != AdapterUnhealthy {
+		t.Errorf("got %s, want the failing adapter unhealthy", state)
+	}
+
+	cancel()
+	if err := <-returned; err != nil {
+		t.Fatalf("got %v, want a clean shutdown", err)
+	}
+	if state := app.AdapterStatuses()[0].State; state != AdapterClosed {
+		t.Errorf("got %s, want the adapter closed", state)
+	}
+}
+
+func TestStepCancelledByContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	started := make(chan struct{})
+	app := NewApp("test", MigrationStep(func(ctx context.Context) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	}))
+	app.SetLogger(NewStdLogger(io.Discard, LevelError))
+
+	returned := make(chan error)
+	go func() { returned <- app.Run(ctx) }()
+	<-started
+	cancel()
+	if err := <-returned; err != nil {
+		t.Fatalf("got %v, want a clean shutdown", err)
+	}
+}
+
+func TestStepNotReportedAsAdapter(t *testing.T) {
+	step := MigrationStep(func(ctx context.Context) error { return nil })
+	app := NewApp("test", step, &recordingAdapter{})
+	app.SetLogger(NewStdLogger(io.Discard, LevelError))
+	events := make(chan Event, 8)
+	app.OnEvent(func(event Event) { events <- event })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	returned := make(chan error)
+	go func() { returned <- app.Run(ctx) }()
+	<-events
+	cancel()
+	if err := <-returned; err != nil {
+		t.Fatalf("got %v, want a clean shutdown", err)
+	}
+
+	close(events)
+	for event := range events {
+		if event.Adapter != "*kurin.recordingAdapter" {
+			t.Errorf("got a %s event of %s, want only the events of the adapter", event.Type, event.Adapter)
+		}
+	}
+	if statuses := app.AdapterStatuses(); len(statuses) != 1 || statuses[0].Name != "*kurin.recordingAdapter" {
+		t.Errorf("got %v, want the status of the adapter only", statuses)
+	}
+}
+
+func TestGracePeriodBoundsPlainClose(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+
+	app := NewApp("test")
+	app.SetLogger(NewStdLogger(io.Discard, LevelFatal))
+	app.RegisterSystems(closeFunc(func() { <-release }))
+	app.SetShutdownGracePeriod(10 * time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	returned := make(chan error)
+	go func() { returned <- app.Run(ctx) }()
+	select {
+	case err := <-returned:
+		if err != nil {
+			t.Fatalf("got %v, want a clean shutdown", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("got Run waiting for the hung system, want it given up on after the grace period")
+	}
+}
+
+func TestFailNotifierKeepsOrder(t *testing.T) {
+	var notifier FailNotifier
+	notifier.Fail(errors.New("dropped before NotifyFail"))
+	c := make(chan error)
+	notifier.NotifyFail(c)
+
+	down := errors.New("down")
+	for i := 0; i < 100; i++ {
+		notifier.Fail(down)
+		notifier.Fail(nil)
+	}
+	for i := 0; i < 200; i++ {
+		want := error(nil)
+		if i%2 == 0 {
+			want = down
+		}
+		if err := <-c; err != want {
+			t.Fatalf("notification %d: got %v, want %v", i, err, want)
+		}
+	}
+}
+
+// failingSystem reports the failures given to its notifier.
+type failingSystem struct {
+	fail FailNotifier
+}
+
+func (system *failingSystem) NotifyFail(c chan error) {
+	system.fail.NotifyFail(c)
+}
+
+// recordingAdapter records the failures forwarded to it.
+type recordingAdapter struct {
+	failures chan error
+}
+
+func (adapter *recordingAdapter) Open()  {}
+func (adapter *recordingAdapter) Close() {}
+func (adapter *recordingAdapter) OnFailure(err error) {
+	adapter.failures <- err
+}
+
+func TestRecoveryForwardedOnceEverySystemRecovered(t *testing.T) {
+	adapter := &recordingAdapter{failures: make(chan error)}
+	redis, sql := &failingSystem{}, &failingSystem{}
+	app := NewApp("test", adapter)
+	app.SetLogger(NewStdLogger(io.Discard, LevelError))
+	app.RegisterSystems(redis, sql)
+	opened := make(chan struct{})
+	app.OnEvent(func(event Event) {
+		if event.Type == EventOpened {
+			close(opened)
+		}
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	returned := make(chan error)
+	go func() { returned <- app.Run(ctx) }()
+	defer func() {
+		cancel()
+		<-returned
+	}()
+	// The failures are only notified once Run subscribed to them, before opening the adapters.
+	<-opened
+
+	redisDown, sqlDown := errors.New("redis down"), errors.New("sql down")
+	steps := []struct {
+		system *failingSystem
+		err    error
+		want   error
+	}{
+		{redis, redisDown, redisDown},
+		{sql, sqlDown, sqlDown},
+		{redis, nil, sqlDown},
+		{sql, nil, nil},
+	}
+	for i, step := range steps {
+		step.system.fail.Fail(step.err)
+		if err := <-adapter.failures; err != step.want {
+			t.Errorf("step %d: got %v forwarded, want %v", i, err, step.want)
+		}
+	}
+}
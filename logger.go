@@ -1,8 +1,11 @@
 package kurin
 
 import (
+	"fmt"
+	"io"
 	"log"
 	"os"
+	"strings"
 )
 
 type (
@@ -15,13 +18,59 @@ type (
 		Panic(args ...interface{})
 	}
 
+	// StructuredLogger is implemented by loggers taking key-value pairs along the message, like
+	// zap's SugaredLogger.
+	StructuredLogger interface {
+		Debugw(msg string, keysAndValues ...interface{})
+		Infow(msg string, keysAndValues ...interface{})
+		Warnw(msg string, keysAndValues ...interface{})
+		Errorw(msg string, keysAndValues ...interface{})
+		Fatalw(msg string, keysAndValues ...interface{})
+		Panicw(msg string, keysAndValues ...interface{})
+	}
+
 	defaultLogger struct {
 		stdout *log.Logger
 		stderr *log.Logger
 	}
+
+	fieldLogger struct {
+		logger        Logger
+		keysAndValues []interface{}
+	}
+
+	// Level is the minimum severity of the entries written by the logger of NewStdLogger.
+	Level int
+
+	stdLogger struct {
+		logger *log.Logger
+		level  Level
+	}
 )
 
-func newDefaultLogger() Logger {
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+	LevelFatal
+	LevelPanic
+)
+
+var levelNames = [...]string{"DEBUG", "INFO", "WARN", "ERROR", "FATAL", "PANIC"}
+
+func (level Level) String() string {
+	if level < LevelDebug || level > LevelPanic {
+		return fmt.Sprintf("Level(%d)", int(level))
+	}
+
+	return levelNames[level]
+}
+
+// NewDefaultLogger returns the logger used when none is given: it writes every entry, timestamped,
+// the debug, info and warn ones to stdout and the others to stderr. Use NewStdLogger to filter
+// the entries by level.
+func NewDefaultLogger() Logger {
 	return &defaultLogger{
 		stdout: log.New(os.Stdout, "", log.LstdFlags),
 		stderr: log.New(os.Stderr, "", log.LstdFlags),
@@ -51,3 +100,160 @@ func (logger *defaultLogger) Fatal(args ...interface{}) {
 func (logger *defaultLogger) Panic(args ...interface{}) {
 	logger.stderr.Panicln(args...)
 }
+
+// NewStdLogger returns a Logger, also a StructuredLogger, writing timestamped entries of at least
+// the given level to out, prefixed by their level, the key-value pairs of the structured methods
+// following the message as key=value. Fatal entries exit the process and panic ones panic,
+// whatever the level.
+func NewStdLogger(out io.Writer, level Level) Logger {
+	return &stdLogger{
+		logger: log.New(out, "", log.LstdFlags),
+		level:  level,
+	}
+}
+
+func (logger *stdLogger) Debug(args ...interface{}) {
+	logger.write(LevelDebug, sprintln(args), nil)
+}
+
+func (logger *stdLogger) Info(args ...interface{}) {
+	logger.write(LevelInfo, sprintln(args), nil)
+}
+
+func (logger *stdLogger) Warn(args ...interface{}) {
+	logger.write(LevelWarn, sprintln(args), nil)
+}
+
+func (logger *stdLogger) Error(args ...interface{}) {
+	logger.write(LevelError, sprintln(args), nil)
+}
+
+func (logger *stdLogger) Fatal(args ...interface{}) {
+	logger.write(LevelFatal, sprintln(args), nil)
+}
+
+func (logger *stdLogger) Panic(args ...interface{}) {
+	logger.write(LevelPanic, sprintln(args), nil)
+}
+
+func (logger *stdLogger) Debugw(msg string, keysAndValues ...interface{}) {
+	logger.write(LevelDebug, msg, keysAndValues)
+}
+
+func (logger *stdLogger) Infow(msg string, keysAndValues ...interface{}) {
+	logger.write(LevelInfo, msg, keysAndValues)
+}
+
+func (logger *stdLogger) Warnw(msg string, keysAndValues ...interface{}) {
+	logger.write(LevelWarn, msg, keysAndValues)
+}
+
+func (logger *stdLogger) Errorw(msg string, keysAndValues ...interface{}) {
+	logger.write(LevelError, msg, keysAndValues)
+}
+
+func (logger *stdLogger) Fatalw(msg string, keysAndValues ...interface{}) {
+	logger.write(LevelFatal, msg, keysAndValues)
+}
+
+func (logger *stdLogger) Panicw(msg string, keysAndValues ...interface{}) {
+	logger.write(LevelPanic, msg, keysAndValues)
+}
+
+func (logger *stdLogger) write(level Level, msg string, keysAndValues []interface{}) {
+	if level >= logger.level || level >= LevelFatal {
+		entry := level.String() + " " + msg + formatFields(keysAndValues)
+		logger.logger.Println(entry)
+	}
+
+	switch level {
+	case LevelFatal:
+		os.Exit(1)
+	case LevelPanic:
+		panic(msg)
+	}
+}
+
+// sprintln formats the arguments as Println does, spaces always added between them, without the
+// trailing newline.
+func sprintln(args []interface{}) string {
+	return strings.TrimSuffix(fmt.Sprintln(args...), "\n")
+}
+
+func formatFields(keysAndValues []interface{}) string {
+	fields := ""
+	for i := 0; i < len(keysAndValues); i += 2 {
+		if i+1 == len(keysAndValues) {
+			fields += fmt.Sprintf(" %v", keysAndValues[i])
+			break
+		}
+		fields += fmt.Sprintf(" %v=%v", keysAndValues[i], keysAndValues[i+1])
+	}
+
+	return fields
+}
+
+// WithFields decorates the logger with key-value pairs added to every entry, as fields when it is
+// a StructuredLogger or following the message as key=value otherwise.
+func WithFields(logger Logger, keysAndValues ...interface{}) Logger {
+	if parent, ok := logger.(*fieldLogger); ok {
+		return &fieldLogger{
+			logger:        parent.logger,
+			keysAndValues: append(append([]interface{}{}, parent.keysAndValues...), keysAndValues...),
+		}
+	}
+
+	return &fieldLogger{logger: logger, keysAndValues: keysAndValues}
+}
+
+func (logger *fieldLogger) Debug(args ...interface{}) {
+	if structured, ok := logger.logger.(StructuredLogger); ok {
+		structured.Debugw(sprintln(args), logger.keysAndValues...)
+		return
+	}
+	logger.logger.Debug(logger.prefixed(args)...)
+}
+
+func (logger *fieldLogger) Info(args ...interface{}) {
+	if structured, ok := logger.logger.(StructuredLogger); ok {
+		structured.Infow(sprintln(args), logger.keysAndValues...)
+		return
+	}
+	logger.logger.Info(logger.prefixed(args)...)
+}
+
+func (logger *fieldLogger) Warn(args ...interface{}) {
+	if structured, ok := logger.logger.(StructuredLogger); ok {
+		structured.Warnw(sprintln(args), logger.keysAndValues...)
+		return
+	}
+	logger.logger.Warn(logger.prefixed(args)...)
+}
+
+func (logger *fieldLogger) Error(args ...interface{}) {
+	if structured, ok := logger.logger.(StructuredLogger); ok {
+		structured.Errorw(sprintln(args), logger.keysAndValues...)
+		return
+	}
+	logger.logger.Error(logger.prefixed(args)...)
+}
+
+func (logger *fieldLogger) Fatal(args ...interface{}) {
+	if structured, ok := logger.logger.(StructuredLogger); ok {
+		structured.Fatalw(sprintln(args), logger.keysAndValues...)
+		return
+	}
+	logger.logger.Fatal(logger.prefixed(args)...)
+}
+
+func (logger *fieldLogger) Panic(args ...interface{}) {
+	if structured, ok := logger.logger.(StructuredLogger); ok {
+		structured.Panicw(sprintln(args), logger.keysAndValues...)
+		return
+	}
+	logger.logger.Panic(logger.prefixed(args)...)
+}
+
+func (logger *fieldLogger) prefixed(args []interface{}) []interface{} {
+	return []interface{}{sprintln(args) + formatFields(logger.keysAndValues)}
+}
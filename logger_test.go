@@ -0,0 +1,76 @@
+package kurin
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"testing"
+)
+
+func TestStdLoggerLevels(t *testing.T) {
+	for _, level := range []Level{LevelDebug, LevelInfo, LevelWarn, LevelError} {
+		var out bytes.Buffer
+		logger := NewStdLogger(&out, level)
+		logger.Debug("debug")
+		logger.Info("info")
+		logger.Warn("warn")
+		logger.Error("error")
+
+		lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+		if len(lines) != int(LevelError-level)+1 {
+			t.Fatalf("%s: got %q, want the entries of level %s and above", level, lines, level)
+		}
+		for i, line := range lines {
+			want := Level(int(level) + i)
+			if !strings.HasSuffix(line, " "+want.String()+" "+strings.ToLower(want.String())) {
+				t.Errorf("%s: got %q, want a %s entry", level, line, want)
+			}
+		}
+	}
+}
+
+func TestStdLoggerPanic(t *testing.T) {
+	var out bytes.Buffer
+	logger := NewStdLogger(&out, LevelError+1)
+	defer func() {
+		if recovered := recover(); recovered != "boom" {
+			t.Errorf("got %v, want a boom panic", recovered)
+		}
+		if !strings.HasSuffix(out.String(), " PANIC boom\n") {
+			t.Errorf("got %q, want the panic entry whatever the level", out.String())
+		}
+	}()
+
+	logger.Panic("boom")
+}
+
+func TestStdLoggerFormat(t *testing.T) {
+	var out bytes.Buffer
+	logger := NewStdLogger(&out, LevelDebug)
+	logger.Info("a", 1)
+	logger.(StructuredLogger).Infow("served", "code", 200, "dangling")
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if !strings.HasSuffix(lines[0], " INFO a 1") {
+		t.Errorf("got %q, want the arguments separated by spaces", lines[0])
+	}
+	if !strings.HasSuffix(lines[1], " INFO served code=200 dangling") {
+		t.Errorf("got %q, want the fields following the message", lines[1])
+	}
+}
+
+func TestDefaultLoggerStreams(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	logger := &defaultLogger{stdout: log.New(&stdout, "", 0), stderr: log.New(&stderr, "", 0)}
+	logger.Debug("debug", 1)
+	logger.Info("info", 2)
+	logger.Warn("warn", 3)
+	logger.Error("error", 4)
+
+	if want := "debug 1\ninfo 2\nwarn 3\n"; stdout.String() != want {
+		t.Errorf("got stdout %q, want %q", stdout.String(), want)
+	}
+	if want := "error 4\n"; stderr.String() != want {
+		t.Errorf("got stderr %q, want %q", stderr.String(), want)
+	}
+}
@@ -0,0 +1,59 @@
+package kurin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+)
+
+type migrationStep struct {
+	run func(ctx context.Context) error
+}
+
+// MigrationStep is a startup step, such as database migrations, given to NewApp among the adapters:
+// Run waits for fn to return before opening the adapters given after it, which thus only start
+// serving, and reporting themselves as ready, once it succeeded. An error aborts the startup, Run
+// then shutting the application down and returning it. A shutdown signal, or the context of Run
+// being done, cancels the context of fn: the error it then returns for this context is not reported.
+func MigrationStep(fn func(ctx context.Context) error) Adapter {
+	return &migrationStep{run: fn}
+}
+
+func (step *migrationStep) Open()           {}
+func (step *migrationStep) Close()          {}
+func (step *migrationStep) OnFailure(error) {}
+
+// runStep runs the step until it returns or a shutdown signal is received, the signal being left
+// for Run to handle. It reports whether the startup can go on.
+func (a *App) runStep(ctx context.Context, step *migrationStep, stop chan os.Signal) (bool, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	a.logger.Info("Running startup step...")
+	done := make(chan error, 1)
+	go func() {
+		done <- step.run(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		if ctx.Err() != nil && errors.Is(err, ctx.Err()) {
+			// The step gave up because the application is shutting down, not because it failed.
+			return false, nil
+		}
+		if err != nil {
+			return false, fmt.Errorf("startup step: %w", err)
+		}
+		return ctx.Err() == nil, nil
+	case sig := <-stop:
+		cancel()
+		<-done
+		// A second signal may already fill stop, which is enough for Run to shut down.
+		select {
+		case stop <- sig:
+		default:
+		}
+		return false, nil
+	}
+}
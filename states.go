@@ -0,0 +1,107 @@
+package kurin
+
+import "fmt"
+
+type (
+	AdapterState int
+
+	// AdapterStatus is the lifecycle state of an adapter under its name, see App.AdapterStatuses.
+	AdapterStatus struct {
+		Name  string
+		State AdapterState
+	}
+)
+
+// AdapterRunning means the adapter was started, its Open or OpenContext running in the background:
+// it is not necessarily listening yet.
+const (
+	AdapterOpening AdapterState = iota
+	AdapterRunning
+	AdapterClosing
+	AdapterClosed
+	AdapterUnhealthy
+)
+
+func (s AdapterState) String() string {
+	switch s {
+	case AdapterOpening:
+		return "opening"
+	case AdapterRunning:
+		return "running"
+	case AdapterClosing:
+		return "closing"
+	case AdapterClosed:
+		return "closed"
+	case AdapterUnhealthy:
+		return "unhealthy"
+	}
+
+	return "unknown"
+}
+
+// AdapterStatuses returns the state of every adapter, in the order they were given, the migration
+// steps aside. A running adapter is unhealthy while it reports itself so as a Healther, or as a
+// Fallible system.
+func (a *App) AdapterStatuses() []AdapterStatus {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	names := a.adapterNames()
+	statuses := make([]AdapterStatus, 0, len(a.adapters))
+	for i, adapter := range a.adapters {
+		if _, ok := adapter.(*migrationStep); ok {
+			continue
+		}
+		state := a.states[i]
+		if healther, ok := adapter.(Healther); ok && state == AdapterRunning && !healther.IsHealthy() {
+			state = AdapterUnhealthy
+		}
+		statuses = append(statuses, AdapterStatus{Name: names[i], State: state})
+	}
+
+	return statuses
+}
+
+// setState sets the state of the adapter of the given index, if any.
+func (a *App) setState(index int, state AdapterState) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if index >= 0 {
+		a.states[index] = state
+	}
+}
+
+// setHealthy flags a running adapter reporting its failure as a Fallible system as unhealthy, or
+// running again once it recovered.
+func (a *App) setHealthy(index int, healthy bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if index < 0 {
+		return
+	}
+	switch {
+	case !healthy && a.states[index] == AdapterRunning:
+		a.states[index] = AdapterUnhealthy
+	case healthy && a.states[index] == AdapterUnhealthy:
+		a.states[index] = AdapterRunning
+	}
+}
+
+// adapterNames names the adapters by their name or after their type, a name appearing more than
+// once getting its rank appended, e.g. "*http.Adapter#2".
+func (a *App) adapterNames() []string {
+	names := make([]string, len(a.adapters))
+	seen := make(map[string]int)
+	for i, adapter := range a.adapters {
+		name := systemName(adapter)
+		seen[name]++
+		if seen[name] > 1 {
+			name = fmt.Sprintf("%s#%d", name, seen[name])
+		}
+		names[i] = name
+	}
+
+	return names
+}
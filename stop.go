@@ -0,0 +1,56 @@
+package kurin
+
+import (
+	"os"
+	"sync"
+)
+
+type (
+	// Stoppable systems notify of their stop every channel given to NotifyStop, a subscription never
+	// replacing a previous one. The channels belong to the subscribers: the system never closes them
+	// and does not block sending on them, so they should be buffered.
+	Stoppable interface {
+		NotifyStop(c chan os.Signal)
+	}
+
+	// StopNotifier implements Stoppable for the systems embedding it, which call Stop once stopping.
+	StopNotifier struct {
+		mu          sync.Mutex
+		subscribers []chan os.Signal
+		signal      os.Signal
+	}
+)
+
+// NotifyStop subscribes c to the stop, it is notified right away when already stopped.
+func (notifier *StopNotifier) NotifyStop(c chan os.Signal) {
+	notifier.mu.Lock()
+	defer notifier.mu.Unlock()
+
+	if notifier.signal != nil {
+		notify(c, notifier.signal)
+		return
+	}
+	notifier.subscribers = append(notifier.subscribers, c)
+}
+
+// Stop notifies every subscriber with sig, only the first call having an effect.
+func (notifier *StopNotifier) Stop(sig os.Signal) {
+	notifier.mu.Lock()
+	defer notifier.mu.Unlock()
+
+	if notifier.signal != nil {
+		return
+	}
+	notifier.signal = sig
+	for _, c := range notifier.subscribers {
+		notify(c, sig)
+	}
+	notifier.subscribers = nil
+}
+
+func notify(c chan os.Signal, sig os.Signal) {
+	select {
+	case c <- sig:
+	default:
+	}
+}
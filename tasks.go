@@ -0,0 +1,132 @@
+package kurin
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+type (
+	// TaskTracker keeps track of the in-flight background tasks of an adapter, so that closing it
+	// can wait for them to complete.
+	TaskTracker struct {
+		logger  Logger
+		mu      sync.Mutex
+		tasks   map[uint64]string
+		next    uint64
+		changed chan struct{}
+		ctx     context.Context
+		cancel  context.CancelFunc
+	}
+
+	taskTrackerKey struct{}
+)
+
+func NewTaskTracker(logger Logger) *TaskTracker {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &TaskTracker{
+		logger:  logger,
+		tasks:   make(map[uint64]string),
+		changed: make(chan struct{}),
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+}
+
+// ContextWithTaskTracker returns a copy of ctx carrying the tracker of the goroutines started by Go,
+// e.g. the one of the HTTP adapter serving the request.
+func ContextWithTaskTracker(ctx context.Context, tracker *TaskTracker) context.Context {
+	return context.WithValue(ctx, taskTrackerKey{}, tracker)
+}
+
+// Go runs fn in a goroutine outliving ctx, as a task of the tracker carried by ctx if any, so
+// that closing its adapter waits for it. The context given to fn carries the values of ctx, e.g.
+// the request ID, but is only cancelled once draining the tracker timed out.
+func Go(ctx context.Context, fn func(context.Context)) {
+	tracker, ok := ctx.Value(taskTrackerKey{}).(*TaskTracker)
+	if !ok {
+		go fn(context.WithoutCancel(ctx))
+		return
+	}
+
+	tracker.GoContext(ctx, "background task", fn)
+}
+
+// Start registers a running task, the returned function must be called once it completed.
+func (tracker *TaskTracker) Start(name string) (done func()) {
+	tracker.mu.Lock()
+	id := tracker.next
+	tracker.next++
+	tracker.tasks[id] = name
+	tracker.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			tracker.mu.Lock()
+			delete(tracker.tasks, id)
+			close(tracker.changed)
+			tracker.changed = make(chan struct{})
+			tracker.mu.Unlock()
+		})
+	}
+}
+
+// Go runs fn in a goroutine as a tracked task.
+func (tracker *TaskTracker) Go(name string, fn func()) {
+	done := tracker.Start(name)
+	go func() {
+		defer done()
+		fn()
+	}()
+}
+
+// GoContext runs fn in a goroutine as a tracked task like Go, with a context carrying the values
+// of ctx and cancelled once draining the tracker timed out.
+func (tracker *TaskTracker) GoContext(ctx context.Context, name string, fn func(context.Context)) {
+	taskCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	stop := context.AfterFunc(tracker.ctx, cancel)
+	tracker.Go(name, func() {
+		defer stop()
+		defer cancel()
+		fn(taskCtx)
+	})
+}
+
+// Running returns the names of the tasks still running, sorted.
+func (tracker *TaskTracker) Running() []string {
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+
+	names := make([]string, 0, len(tracker.tasks))
+	for _, name := range tracker.tasks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Drain waits for the running tasks to complete. When ctx is done first, it cancels the context of
+// the tasks started with GoContext, logs the tasks still running and returns the context error.
+func (tracker *TaskTracker) Drain(ctx context.Context) error {
+	for {
+		tracker.mu.Lock()
+		remaining, changed := len(tracker.tasks), tracker.changed
+		tracker.mu.Unlock()
+		if remaining == 0 {
+			return nil
+		}
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			tracker.cancel()
+			tracker.logger.Warn(fmt.Sprintf("Tasks still running after draining: %s", strings.Join(tracker.Running(), ", ")))
+			return ctx.Err()
+		}
+	}
+}